@@ -0,0 +1,15 @@
+// Package tcliservice is the thrift client for hiveserver2's TCLIService,
+// generated from hive 0.13's TCLIService.thrift, for protocol versions V1
+// to V3.
+//
+// operationstatus.go and progressupdate.go are not generated.
+// operationstatus.go holds TGetOperationStatusReq and
+// TGetOperationStatusResp, written by hand with fields that later versions
+// of the IDL added, and ttypes.go was edited to remove the generated
+// versions of those types. progressupdate.go holds TProgressUpdateResp and
+// TJobExecutionStatus, which hive 0.13's IDL doesn't have at all. So
+// regenerating from hive 0.13's IDL doesn't compile as is: delete
+// TGetOperationStatusReq and TGetOperationStatusResp, and their methods,
+// from the new ttypes.go by hand. Regenerating from hive 2.3's IDL, or a
+// later one, instead replaces both files, which should then be deleted.
+package tcliservice
@@ -0,0 +1,476 @@
+// Not generated by thrift; written by hand, following the thrift
+// compiler's output, in place of the generated TGetOperationStatusReq and
+// TGetOperationStatusResp removed from ttypes.go. See the package doc for
+// regenerating. They implement these declarations, from hive 2.3's
+// TCLIService.thrift, where hive 0.13's has only operationHandle, status
+// and operationState:
+//
+//	struct TGetOperationStatusReq {
+//	  1: required TOperationHandle operationHandle
+//	  2: optional bool getProgressUpdate
+//	}
+//
+//	struct TGetOperationStatusResp {
+//	  1: required TStatus status
+//	  2: optional TOperationState operationState
+//	  3: optional string sqlState
+//	  4: optional i32 errorCode
+//	  5: optional string errorMessage
+//	  ...
+//	  10: optional TProgressUpdateResp progressUpdateResponse
+//	}
+//
+// Fields 6 to 9 aren't used, so they're left out and skipped when read.
+// Servers that predate a field leave it unset, and ones that don't know it
+// skip it. TProgressUpdateResp is in progressupdate.go.
+
+package tcliservice
+
+import (
+	"fmt"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+)
+
+type TGetOperationStatusReq struct {
+	OperationHandle   TOperationHandle `thrift:"operationHandle,1,required"`
+	GetProgressUpdate *bool            `thrift:"getProgressUpdate,2"`
+}
+
+func NewTGetOperationStatusReq() *TGetOperationStatusReq {
+	return &TGetOperationStatusReq{}
+}
+
+func (p *TGetOperationStatusReq) GetOperationHandle() TOperationHandle {
+	return p.OperationHandle
+}
+
+var TGetOperationStatusReq_GetProgressUpdate_DEFAULT bool
+
+func (p *TGetOperationStatusReq) GetGetProgressUpdate() bool {
+	if !p.IsSetGetProgressUpdate() {
+		return TGetOperationStatusReq_GetProgressUpdate_DEFAULT
+	}
+	return *p.GetProgressUpdate
+}
+func (p *TGetOperationStatusReq) IsSetGetProgressUpdate() bool {
+	return p.GetProgressUpdate != nil
+}
+func (p *TGetOperationStatusReq) Read(iprot thrift.TProtocol) error {
+	if _, err := iprot.ReadStructBegin(); err != nil {
+		return fmt.Errorf("%T read error: %s", p, err)
+	}
+	for {
+		_, fieldTypeId, fieldId, err := iprot.ReadFieldBegin()
+		if err != nil {
+			return fmt.Errorf("%T field %d read error: %s", p, fieldId, err)
+		}
+		if fieldTypeId == thrift.STOP {
+			break
+		}
+		switch fieldId {
+		case 1:
+			if err := p.ReadField1(iprot); err != nil {
+				return err
+			}
+		case 2:
+			if err := p.ReadField2(iprot); err != nil {
+				return err
+			}
+		default:
+			if err := iprot.Skip(fieldTypeId); err != nil {
+				return err
+			}
+		}
+		if err := iprot.ReadFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if err := iprot.ReadStructEnd(); err != nil {
+		return fmt.Errorf("%T read struct end error: %s", p, err)
+	}
+	return nil
+}
+
+func (p *TGetOperationStatusReq) ReadField1(iprot thrift.TProtocol) error {
+	p.OperationHandle = TOperationHandle{}
+	if err := p.OperationHandle.Read(iprot); err != nil {
+		return fmt.Errorf("%T error reading struct: %s", p.OperationHandle, err)
+	}
+	return nil
+}
+
+func (p *TGetOperationStatusReq) ReadField2(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadBool(); err != nil {
+		return fmt.Errorf("error reading field 2: %s", err)
+	} else {
+		p.GetProgressUpdate = &v
+	}
+	return nil
+}
+
+func (p *TGetOperationStatusReq) Write(oprot thrift.TProtocol) error {
+	if err := oprot.WriteStructBegin("TGetOperationStatusReq"); err != nil {
+		return fmt.Errorf("%T write struct begin error: %s", p, err)
+	}
+	if err := p.writeField1(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField2(oprot); err != nil {
+		return err
+	}
+	if err := oprot.WriteFieldStop(); err != nil {
+		return fmt.Errorf("write field stop error: %s", err)
+	}
+	if err := oprot.WriteStructEnd(); err != nil {
+		return fmt.Errorf("write struct stop error: %s", err)
+	}
+	return nil
+}
+
+func (p *TGetOperationStatusReq) writeField1(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("operationHandle", thrift.STRUCT, 1); err != nil {
+		return fmt.Errorf("%T write field begin error 1:operationHandle: %s", p, err)
+	}
+	if err := p.OperationHandle.Write(oprot); err != nil {
+		return fmt.Errorf("%T error writing struct: %s", p.OperationHandle, err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 1:operationHandle: %s", p, err)
+	}
+	return err
+}
+
+func (p *TGetOperationStatusReq) writeField2(oprot thrift.TProtocol) (err error) {
+	if p.IsSetGetProgressUpdate() {
+		if err := oprot.WriteFieldBegin("getProgressUpdate", thrift.BOOL, 2); err != nil {
+			return fmt.Errorf("%T write field begin error 2:getProgressUpdate: %s", p, err)
+		}
+		if err := oprot.WriteBool(bool(*p.GetProgressUpdate)); err != nil {
+			return fmt.Errorf("%T.getProgressUpdate (2) field write error: %s", p, err)
+		}
+		if err := oprot.WriteFieldEnd(); err != nil {
+			return fmt.Errorf("%T write field end error 2:getProgressUpdate: %s", p, err)
+		}
+	}
+	return err
+}
+
+func (p *TGetOperationStatusReq) String() string {
+	if p == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("TGetOperationStatusReq(%+v)", *p)
+}
+
+type TGetOperationStatusResp struct {
+	Status                 TStatus              `thrift:"status,1,required"`
+	OperationState         *TOperationState     `thrift:"operationState,2"`
+	SqlState               *string              `thrift:"sqlState,3"`
+	ErrorCode              *int32               `thrift:"errorCode,4"`
+	ErrorMessage           *string              `thrift:"errorMessage,5"`
+	ProgressUpdateResponse *TProgressUpdateResp `thrift:"progressUpdateResponse,10"`
+}
+
+func NewTGetOperationStatusResp() *TGetOperationStatusResp {
+	return &TGetOperationStatusResp{}
+}
+
+func (p *TGetOperationStatusResp) GetStatus() TStatus {
+	return p.Status
+}
+
+var TGetOperationStatusResp_OperationState_DEFAULT TOperationState
+
+func (p *TGetOperationStatusResp) GetOperationState() TOperationState {
+	if !p.IsSetOperationState() {
+		return TGetOperationStatusResp_OperationState_DEFAULT
+	}
+	return *p.OperationState
+}
+
+var TGetOperationStatusResp_SqlState_DEFAULT string
+
+func (p *TGetOperationStatusResp) GetSqlState() string {
+	if !p.IsSetSqlState() {
+		return TGetOperationStatusResp_SqlState_DEFAULT
+	}
+	return *p.SqlState
+}
+
+var TGetOperationStatusResp_ErrorCode_DEFAULT int32
+
+func (p *TGetOperationStatusResp) GetErrorCode() int32 {
+	if !p.IsSetErrorCode() {
+		return TGetOperationStatusResp_ErrorCode_DEFAULT
+	}
+	return *p.ErrorCode
+}
+
+var TGetOperationStatusResp_ErrorMessage_DEFAULT string
+
+func (p *TGetOperationStatusResp) GetErrorMessage() string {
+	if !p.IsSetErrorMessage() {
+		return TGetOperationStatusResp_ErrorMessage_DEFAULT
+	}
+	return *p.ErrorMessage
+}
+
+var TGetOperationStatusResp_ProgressUpdateResponse_DEFAULT *TProgressUpdateResp
+
+func (p *TGetOperationStatusResp) GetProgressUpdateResponse() *TProgressUpdateResp {
+	if !p.IsSetProgressUpdateResponse() {
+		return TGetOperationStatusResp_ProgressUpdateResponse_DEFAULT
+	}
+	return p.ProgressUpdateResponse
+}
+func (p *TGetOperationStatusResp) IsSetOperationState() bool {
+	return p.OperationState != nil
+}
+
+func (p *TGetOperationStatusResp) IsSetSqlState() bool {
+	return p.SqlState != nil
+}
+
+func (p *TGetOperationStatusResp) IsSetErrorCode() bool {
+	return p.ErrorCode != nil
+}
+
+func (p *TGetOperationStatusResp) IsSetErrorMessage() bool {
+	return p.ErrorMessage != nil
+}
+
+func (p *TGetOperationStatusResp) IsSetProgressUpdateResponse() bool {
+	return p.ProgressUpdateResponse != nil
+}
+
+func (p *TGetOperationStatusResp) Read(iprot thrift.TProtocol) error {
+	if _, err := iprot.ReadStructBegin(); err != nil {
+		return fmt.Errorf("%T read error: %s", p, err)
+	}
+	for {
+		_, fieldTypeId, fieldId, err := iprot.ReadFieldBegin()
+		if err != nil {
+			return fmt.Errorf("%T field %d read error: %s", p, fieldId, err)
+		}
+		if fieldTypeId == thrift.STOP {
+			break
+		}
+		switch fieldId {
+		case 1:
+			if err := p.ReadField1(iprot); err != nil {
+				return err
+			}
+		case 2:
+			if err := p.ReadField2(iprot); err != nil {
+				return err
+			}
+		case 3:
+			if err := p.ReadField3(iprot); err != nil {
+				return err
+			}
+		case 4:
+			if err := p.ReadField4(iprot); err != nil {
+				return err
+			}
+		case 5:
+			if err := p.ReadField5(iprot); err != nil {
+				return err
+			}
+		case 10:
+			if err := p.ReadField10(iprot); err != nil {
+				return err
+			}
+		default:
+			if err := iprot.Skip(fieldTypeId); err != nil {
+				return err
+			}
+		}
+		if err := iprot.ReadFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if err := iprot.ReadStructEnd(); err != nil {
+		return fmt.Errorf("%T read struct end error: %s", p, err)
+	}
+	return nil
+}
+
+func (p *TGetOperationStatusResp) ReadField1(iprot thrift.TProtocol) error {
+	p.Status = TStatus{}
+	if err := p.Status.Read(iprot); err != nil {
+		return fmt.Errorf("%T error reading struct: %s", p.Status, err)
+	}
+	return nil
+}
+
+func (p *TGetOperationStatusResp) ReadField2(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadI32(); err != nil {
+		return fmt.Errorf("error reading field 2: %s", err)
+	} else {
+		temp := TOperationState(v)
+		p.OperationState = &temp
+	}
+	return nil
+}
+
+func (p *TGetOperationStatusResp) ReadField3(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadString(); err != nil {
+		return fmt.Errorf("error reading field 3: %s", err)
+	} else {
+		p.SqlState = &v
+	}
+	return nil
+}
+
+func (p *TGetOperationStatusResp) ReadField4(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadI32(); err != nil {
+		return fmt.Errorf("error reading field 4: %s", err)
+	} else {
+		p.ErrorCode = &v
+	}
+	return nil
+}
+
+func (p *TGetOperationStatusResp) ReadField5(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadString(); err != nil {
+		return fmt.Errorf("error reading field 5: %s", err)
+	} else {
+		p.ErrorMessage = &v
+	}
+	return nil
+}
+
+func (p *TGetOperationStatusResp) ReadField10(iprot thrift.TProtocol) error {
+	p.ProgressUpdateResponse = &TProgressUpdateResp{}
+	if err := p.ProgressUpdateResponse.Read(iprot); err != nil {
+		return fmt.Errorf("%T error reading struct: %s", p.ProgressUpdateResponse, err)
+	}
+	return nil
+}
+
+func (p *TGetOperationStatusResp) Write(oprot thrift.TProtocol) error {
+	if err := oprot.WriteStructBegin("TGetOperationStatusResp"); err != nil {
+		return fmt.Errorf("%T write struct begin error: %s", p, err)
+	}
+	if err := p.writeField1(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField2(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField3(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField4(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField5(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField10(oprot); err != nil {
+		return err
+	}
+	if err := oprot.WriteFieldStop(); err != nil {
+		return fmt.Errorf("write field stop error: %s", err)
+	}
+	if err := oprot.WriteStructEnd(); err != nil {
+		return fmt.Errorf("write struct stop error: %s", err)
+	}
+	return nil
+}
+
+func (p *TGetOperationStatusResp) writeField1(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("status", thrift.STRUCT, 1); err != nil {
+		return fmt.Errorf("%T write field begin error 1:status: %s", p, err)
+	}
+	if err := p.Status.Write(oprot); err != nil {
+		return fmt.Errorf("%T error writing struct: %s", p.Status, err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 1:status: %s", p, err)
+	}
+	return err
+}
+
+func (p *TGetOperationStatusResp) writeField2(oprot thrift.TProtocol) (err error) {
+	if p.IsSetOperationState() {
+		if err := oprot.WriteFieldBegin("operationState", thrift.I32, 2); err != nil {
+			return fmt.Errorf("%T write field begin error 2:operationState: %s", p, err)
+		}
+		if err := oprot.WriteI32(int32(*p.OperationState)); err != nil {
+			return fmt.Errorf("%T.operationState (2) field write error: %s", p, err)
+		}
+		if err := oprot.WriteFieldEnd(); err != nil {
+			return fmt.Errorf("%T write field end error 2:operationState: %s", p, err)
+		}
+	}
+	return err
+}
+
+func (p *TGetOperationStatusResp) writeField3(oprot thrift.TProtocol) (err error) {
+	if p.IsSetSqlState() {
+		if err := oprot.WriteFieldBegin("sqlState", thrift.STRING, 3); err != nil {
+			return fmt.Errorf("%T write field begin error 3:sqlState: %s", p, err)
+		}
+		if err := oprot.WriteString(string(*p.SqlState)); err != nil {
+			return fmt.Errorf("%T.sqlState (3) field write error: %s", p, err)
+		}
+		if err := oprot.WriteFieldEnd(); err != nil {
+			return fmt.Errorf("%T write field end error 3:sqlState: %s", p, err)
+		}
+	}
+	return err
+}
+
+func (p *TGetOperationStatusResp) writeField4(oprot thrift.TProtocol) (err error) {
+	if p.IsSetErrorCode() {
+		if err := oprot.WriteFieldBegin("errorCode", thrift.I32, 4); err != nil {
+			return fmt.Errorf("%T write field begin error 4:errorCode: %s", p, err)
+		}
+		if err := oprot.WriteI32(int32(*p.ErrorCode)); err != nil {
+			return fmt.Errorf("%T.errorCode (4) field write error: %s", p, err)
+		}
+		if err := oprot.WriteFieldEnd(); err != nil {
+			return fmt.Errorf("%T write field end error 4:errorCode: %s", p, err)
+		}
+	}
+	return err
+}
+
+func (p *TGetOperationStatusResp) writeField5(oprot thrift.TProtocol) (err error) {
+	if p.IsSetErrorMessage() {
+		if err := oprot.WriteFieldBegin("errorMessage", thrift.STRING, 5); err != nil {
+			return fmt.Errorf("%T write field begin error 5:errorMessage: %s", p, err)
+		}
+		if err := oprot.WriteString(string(*p.ErrorMessage)); err != nil {
+			return fmt.Errorf("%T.errorMessage (5) field write error: %s", p, err)
+		}
+		if err := oprot.WriteFieldEnd(); err != nil {
+			return fmt.Errorf("%T write field end error 5:errorMessage: %s", p, err)
+		}
+	}
+	return err
+}
+
+func (p *TGetOperationStatusResp) writeField10(oprot thrift.TProtocol) (err error) {
+	if p.IsSetProgressUpdateResponse() {
+		if err := oprot.WriteFieldBegin("progressUpdateResponse", thrift.STRUCT, 10); err != nil {
+			return fmt.Errorf("%T write field begin error 10:progressUpdateResponse: %s", p, err)
+		}
+		if err := p.ProgressUpdateResponse.Write(oprot); err != nil {
+			return fmt.Errorf("%T error writing struct: %s", p.ProgressUpdateResponse, err)
+		}
+		if err := oprot.WriteFieldEnd(); err != nil {
+			return fmt.Errorf("%T write field end error 10:progressUpdateResponse: %s", p, err)
+		}
+	}
+	return err
+}
+
+func (p *TGetOperationStatusResp) String() string {
+	if p == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("TGetOperationStatusResp(%+v)", *p)
+}
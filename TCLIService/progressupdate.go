@@ -0,0 +1,382 @@
+// Not generated by thrift; written by hand, following the thrift
+// compiler's output, for the progress updates that hive 2.3 added to
+// TGetOperationStatusResp. See the package doc for regenerating. They
+// implement these declarations from hive 2.3's TCLIService.thrift:
+//
+//	enum TJobExecutionStatus {
+//	  IN_PROGRESS,
+//	  COMPLETE,
+//	  NOT_AVAILABLE
+//	}
+//
+//	struct TProgressUpdateResp {
+//	  1: required list<string> headerNames,
+//	  2: required list<list<string>> rows,
+//	  3: required double progressedPercentage,
+//	  4: required TJobExecutionStatus status,
+//	  5: required string footerSummary,
+//	  6: required i64 startTime
+//	}
+
+package tcliservice
+
+import (
+	"fmt"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+)
+
+type TJobExecutionStatus int64
+
+const (
+	TJobExecutionStatus_IN_PROGRESS   TJobExecutionStatus = 0
+	TJobExecutionStatus_COMPLETE      TJobExecutionStatus = 1
+	TJobExecutionStatus_NOT_AVAILABLE TJobExecutionStatus = 2
+)
+
+func (p TJobExecutionStatus) String() string {
+	switch p {
+	case TJobExecutionStatus_IN_PROGRESS:
+		return "TJobExecutionStatus_IN_PROGRESS"
+	case TJobExecutionStatus_COMPLETE:
+		return "TJobExecutionStatus_COMPLETE"
+	case TJobExecutionStatus_NOT_AVAILABLE:
+		return "TJobExecutionStatus_NOT_AVAILABLE"
+	}
+	return "<UNSET>"
+}
+
+func TJobExecutionStatusFromString(s string) (TJobExecutionStatus, error) {
+	switch s {
+	case "TJobExecutionStatus_IN_PROGRESS":
+		return TJobExecutionStatus_IN_PROGRESS, nil
+	case "TJobExecutionStatus_COMPLETE":
+		return TJobExecutionStatus_COMPLETE, nil
+	case "TJobExecutionStatus_NOT_AVAILABLE":
+		return TJobExecutionStatus_NOT_AVAILABLE, nil
+	}
+	return TJobExecutionStatus(0), fmt.Errorf("not a valid TJobExecutionStatus string")
+}
+
+func TJobExecutionStatusPtr(v TJobExecutionStatus) *TJobExecutionStatus { return &v }
+
+type TProgressUpdateResp struct {
+	HeaderNames          []string            `thrift:"headerNames,1,required"`
+	Rows                 [][]string          `thrift:"rows,2,required"`
+	ProgressedPercentage float64             `thrift:"progressedPercentage,3,required"`
+	Status               TJobExecutionStatus `thrift:"status,4,required"`
+	FooterSummary        string              `thrift:"footerSummary,5,required"`
+	StartTime            int64               `thrift:"startTime,6,required"`
+}
+
+func NewTProgressUpdateResp() *TProgressUpdateResp {
+	return &TProgressUpdateResp{}
+}
+
+func (p *TProgressUpdateResp) GetHeaderNames() []string {
+	return p.HeaderNames
+}
+
+func (p *TProgressUpdateResp) GetRows() [][]string {
+	return p.Rows
+}
+
+func (p *TProgressUpdateResp) GetProgressedPercentage() float64 {
+	return p.ProgressedPercentage
+}
+
+func (p *TProgressUpdateResp) GetStatus() TJobExecutionStatus {
+	return p.Status
+}
+
+func (p *TProgressUpdateResp) GetFooterSummary() string {
+	return p.FooterSummary
+}
+
+func (p *TProgressUpdateResp) GetStartTime() int64 {
+	return p.StartTime
+}
+func (p *TProgressUpdateResp) Read(iprot thrift.TProtocol) error {
+	if _, err := iprot.ReadStructBegin(); err != nil {
+		return fmt.Errorf("%T read error: %s", p, err)
+	}
+	for {
+		_, fieldTypeId, fieldId, err := iprot.ReadFieldBegin()
+		if err != nil {
+			return fmt.Errorf("%T field %d read error: %s", p, fieldId, err)
+		}
+		if fieldTypeId == thrift.STOP {
+			break
+		}
+		switch fieldId {
+		case 1:
+			if err := p.ReadField1(iprot); err != nil {
+				return err
+			}
+		case 2:
+			if err := p.ReadField2(iprot); err != nil {
+				return err
+			}
+		case 3:
+			if err := p.ReadField3(iprot); err != nil {
+				return err
+			}
+		case 4:
+			if err := p.ReadField4(iprot); err != nil {
+				return err
+			}
+		case 5:
+			if err := p.ReadField5(iprot); err != nil {
+				return err
+			}
+		case 6:
+			if err := p.ReadField6(iprot); err != nil {
+				return err
+			}
+		default:
+			if err := iprot.Skip(fieldTypeId); err != nil {
+				return err
+			}
+		}
+		if err := iprot.ReadFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if err := iprot.ReadStructEnd(); err != nil {
+		return fmt.Errorf("%T read struct end error: %s", p, err)
+	}
+	return nil
+}
+
+func (p *TProgressUpdateResp) ReadField1(iprot thrift.TProtocol) error {
+	_, size, err := iprot.ReadListBegin()
+	if err != nil {
+		return fmt.Errorf("error reading list begin: %s", err)
+	}
+	tSlice := make([]string, 0, size)
+	p.HeaderNames = tSlice
+	for i := 0; i < size; i++ {
+		var _elem0 string
+		if v, err := iprot.ReadString(); err != nil {
+			return fmt.Errorf("error reading field 0: %s", err)
+		} else {
+			_elem0 = v
+		}
+		p.HeaderNames = append(p.HeaderNames, _elem0)
+	}
+	if err := iprot.ReadListEnd(); err != nil {
+		return fmt.Errorf("error reading list end: %s", err)
+	}
+	return nil
+}
+
+func (p *TProgressUpdateResp) ReadField2(iprot thrift.TProtocol) error {
+	_, size, err := iprot.ReadListBegin()
+	if err != nil {
+		return fmt.Errorf("error reading list begin: %s", err)
+	}
+	tSlice := make([][]string, 0, size)
+	p.Rows = tSlice
+	for i := 0; i < size; i++ {
+		_, size, err := iprot.ReadListBegin()
+		if err != nil {
+			return fmt.Errorf("error reading list begin: %s", err)
+		}
+		tSlice := make([]string, 0, size)
+		_elem1 := tSlice
+		for i := 0; i < size; i++ {
+			var _elem2 string
+			if v, err := iprot.ReadString(); err != nil {
+				return fmt.Errorf("error reading field 0: %s", err)
+			} else {
+				_elem2 = v
+			}
+			_elem1 = append(_elem1, _elem2)
+		}
+		if err := iprot.ReadListEnd(); err != nil {
+			return fmt.Errorf("error reading list end: %s", err)
+		}
+		p.Rows = append(p.Rows, _elem1)
+	}
+	if err := iprot.ReadListEnd(); err != nil {
+		return fmt.Errorf("error reading list end: %s", err)
+	}
+	return nil
+}
+
+func (p *TProgressUpdateResp) ReadField3(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadDouble(); err != nil {
+		return fmt.Errorf("error reading field 3: %s", err)
+	} else {
+		p.ProgressedPercentage = v
+	}
+	return nil
+}
+
+func (p *TProgressUpdateResp) ReadField4(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadI32(); err != nil {
+		return fmt.Errorf("error reading field 4: %s", err)
+	} else {
+		temp := TJobExecutionStatus(v)
+		p.Status = temp
+	}
+	return nil
+}
+
+func (p *TProgressUpdateResp) ReadField5(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadString(); err != nil {
+		return fmt.Errorf("error reading field 5: %s", err)
+	} else {
+		p.FooterSummary = v
+	}
+	return nil
+}
+
+func (p *TProgressUpdateResp) ReadField6(iprot thrift.TProtocol) error {
+	if v, err := iprot.ReadI64(); err != nil {
+		return fmt.Errorf("error reading field 6: %s", err)
+	} else {
+		p.StartTime = v
+	}
+	return nil
+}
+
+func (p *TProgressUpdateResp) Write(oprot thrift.TProtocol) error {
+	if err := oprot.WriteStructBegin("TProgressUpdateResp"); err != nil {
+		return fmt.Errorf("%T write struct begin error: %s", p, err)
+	}
+	if err := p.writeField1(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField2(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField3(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField4(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField5(oprot); err != nil {
+		return err
+	}
+	if err := p.writeField6(oprot); err != nil {
+		return err
+	}
+	if err := oprot.WriteFieldStop(); err != nil {
+		return fmt.Errorf("write field stop error: %s", err)
+	}
+	if err := oprot.WriteStructEnd(); err != nil {
+		return fmt.Errorf("write struct stop error: %s", err)
+	}
+	return nil
+}
+
+func (p *TProgressUpdateResp) writeField1(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("headerNames", thrift.LIST, 1); err != nil {
+		return fmt.Errorf("%T write field begin error 1:headerNames: %s", p, err)
+	}
+	if err := oprot.WriteListBegin(thrift.STRING, len(p.HeaderNames)); err != nil {
+		return fmt.Errorf("error writing list begin: %s", err)
+	}
+	for _, v := range p.HeaderNames {
+		if err := oprot.WriteString(string(v)); err != nil {
+			return fmt.Errorf("%T. (0) field write error: %s", p, err)
+		}
+	}
+	if err := oprot.WriteListEnd(); err != nil {
+		return fmt.Errorf("error writing list end: %s", err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 1:headerNames: %s", p, err)
+	}
+	return err
+}
+
+func (p *TProgressUpdateResp) writeField2(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("rows", thrift.LIST, 2); err != nil {
+		return fmt.Errorf("%T write field begin error 2:rows: %s", p, err)
+	}
+	if err := oprot.WriteListBegin(thrift.LIST, len(p.Rows)); err != nil {
+		return fmt.Errorf("error writing list begin: %s", err)
+	}
+	for _, v := range p.Rows {
+		if err := oprot.WriteListBegin(thrift.STRING, len(v)); err != nil {
+			return fmt.Errorf("error writing list begin: %s", err)
+		}
+		for _, v := range v {
+			if err := oprot.WriteString(string(v)); err != nil {
+				return fmt.Errorf("%T. (0) field write error: %s", p, err)
+			}
+		}
+		if err := oprot.WriteListEnd(); err != nil {
+			return fmt.Errorf("error writing list end: %s", err)
+		}
+	}
+	if err := oprot.WriteListEnd(); err != nil {
+		return fmt.Errorf("error writing list end: %s", err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 2:rows: %s", p, err)
+	}
+	return err
+}
+
+func (p *TProgressUpdateResp) writeField3(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("progressedPercentage", thrift.DOUBLE, 3); err != nil {
+		return fmt.Errorf("%T write field begin error 3:progressedPercentage: %s", p, err)
+	}
+	if err := oprot.WriteDouble(float64(p.ProgressedPercentage)); err != nil {
+		return fmt.Errorf("%T.progressedPercentage (3) field write error: %s", p, err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 3:progressedPercentage: %s", p, err)
+	}
+	return err
+}
+
+func (p *TProgressUpdateResp) writeField4(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("status", thrift.I32, 4); err != nil {
+		return fmt.Errorf("%T write field begin error 4:status: %s", p, err)
+	}
+	if err := oprot.WriteI32(int32(p.Status)); err != nil {
+		return fmt.Errorf("%T.status (4) field write error: %s", p, err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 4:status: %s", p, err)
+	}
+	return err
+}
+
+func (p *TProgressUpdateResp) writeField5(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("footerSummary", thrift.STRING, 5); err != nil {
+		return fmt.Errorf("%T write field begin error 5:footerSummary: %s", p, err)
+	}
+	if err := oprot.WriteString(string(p.FooterSummary)); err != nil {
+		return fmt.Errorf("%T.footerSummary (5) field write error: %s", p, err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 5:footerSummary: %s", p, err)
+	}
+	return err
+}
+
+func (p *TProgressUpdateResp) writeField6(oprot thrift.TProtocol) (err error) {
+	if err := oprot.WriteFieldBegin("startTime", thrift.I64, 6); err != nil {
+		return fmt.Errorf("%T write field begin error 6:startTime: %s", p, err)
+	}
+	if err := oprot.WriteI64(int64(p.StartTime)); err != nil {
+		return fmt.Errorf("%T.startTime (6) field write error: %s", p, err)
+	}
+	if err := oprot.WriteFieldEnd(); err != nil {
+		return fmt.Errorf("%T write field end error 6:startTime: %s", p, err)
+	}
+	return err
+}
+
+func (p *TProgressUpdateResp) String() string {
+	if p == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("TProgressUpdateResp(%+v)", *p)
+}
@@ -8321,226 +8321,6 @@ func (p *TGetFunctionsResp) String() string {
 	return fmt.Sprintf("TGetFunctionsResp(%+v)", *p)
 }
 
-type TGetOperationStatusReq struct {
-	OperationHandle TOperationHandle `thrift:"operationHandle,1,required"`
-}
-
-func NewTGetOperationStatusReq() *TGetOperationStatusReq {
-	return &TGetOperationStatusReq{}
-}
-
-func (p *TGetOperationStatusReq) GetOperationHandle() TOperationHandle {
-	return p.OperationHandle
-}
-func (p *TGetOperationStatusReq) Read(iprot thrift.TProtocol) error {
-	if _, err := iprot.ReadStructBegin(); err != nil {
-		return fmt.Errorf("%T read error: %s", p, err)
-	}
-	for {
-		_, fieldTypeId, fieldId, err := iprot.ReadFieldBegin()
-		if err != nil {
-			return fmt.Errorf("%T field %d read error: %s", p, fieldId, err)
-		}
-		if fieldTypeId == thrift.STOP {
-			break
-		}
-		switch fieldId {
-		case 1:
-			if err := p.ReadField1(iprot); err != nil {
-				return err
-			}
-		default:
-			if err := iprot.Skip(fieldTypeId); err != nil {
-				return err
-			}
-		}
-		if err := iprot.ReadFieldEnd(); err != nil {
-			return err
-		}
-	}
-	if err := iprot.ReadStructEnd(); err != nil {
-		return fmt.Errorf("%T read struct end error: %s", p, err)
-	}
-	return nil
-}
-
-func (p *TGetOperationStatusReq) ReadField1(iprot thrift.TProtocol) error {
-	p.OperationHandle = TOperationHandle{}
-	if err := p.OperationHandle.Read(iprot); err != nil {
-		return fmt.Errorf("%T error reading struct: %s", p.OperationHandle, err)
-	}
-	return nil
-}
-
-func (p *TGetOperationStatusReq) Write(oprot thrift.TProtocol) error {
-	if err := oprot.WriteStructBegin("TGetOperationStatusReq"); err != nil {
-		return fmt.Errorf("%T write struct begin error: %s", p, err)
-	}
-	if err := p.writeField1(oprot); err != nil {
-		return err
-	}
-	if err := oprot.WriteFieldStop(); err != nil {
-		return fmt.Errorf("write field stop error: %s", err)
-	}
-	if err := oprot.WriteStructEnd(); err != nil {
-		return fmt.Errorf("write struct stop error: %s", err)
-	}
-	return nil
-}
-
-func (p *TGetOperationStatusReq) writeField1(oprot thrift.TProtocol) (err error) {
-	if err := oprot.WriteFieldBegin("operationHandle", thrift.STRUCT, 1); err != nil {
-		return fmt.Errorf("%T write field begin error 1:operationHandle: %s", p, err)
-	}
-	if err := p.OperationHandle.Write(oprot); err != nil {
-		return fmt.Errorf("%T error writing struct: %s", p.OperationHandle, err)
-	}
-	if err := oprot.WriteFieldEnd(); err != nil {
-		return fmt.Errorf("%T write field end error 1:operationHandle: %s", p, err)
-	}
-	return err
-}
-
-func (p *TGetOperationStatusReq) String() string {
-	if p == nil {
-		return "<nil>"
-	}
-	return fmt.Sprintf("TGetOperationStatusReq(%+v)", *p)
-}
-
-type TGetOperationStatusResp struct {
-	Status         TStatus          `thrift:"status,1,required"`
-	OperationState *TOperationState `thrift:"operationState,2"`
-}
-
-func NewTGetOperationStatusResp() *TGetOperationStatusResp {
-	return &TGetOperationStatusResp{}
-}
-
-func (p *TGetOperationStatusResp) GetStatus() TStatus {
-	return p.Status
-}
-
-var TGetOperationStatusResp_OperationState_DEFAULT TOperationState
-
-func (p *TGetOperationStatusResp) GetOperationState() TOperationState {
-	if !p.IsSetOperationState() {
-		return TGetOperationStatusResp_OperationState_DEFAULT
-	}
-	return *p.OperationState
-}
-func (p *TGetOperationStatusResp) IsSetOperationState() bool {
-	return p.OperationState != nil
-}
-
-func (p *TGetOperationStatusResp) Read(iprot thrift.TProtocol) error {
-	if _, err := iprot.ReadStructBegin(); err != nil {
-		return fmt.Errorf("%T read error: %s", p, err)
-	}
-	for {
-		_, fieldTypeId, fieldId, err := iprot.ReadFieldBegin()
-		if err != nil {
-			return fmt.Errorf("%T field %d read error: %s", p, fieldId, err)
-		}
-		if fieldTypeId == thrift.STOP {
-			break
-		}
-		switch fieldId {
-		case 1:
-			if err := p.ReadField1(iprot); err != nil {
-				return err
-			}
-		case 2:
-			if err := p.ReadField2(iprot); err != nil {
-				return err
-			}
-		default:
-			if err := iprot.Skip(fieldTypeId); err != nil {
-				return err
-			}
-		}
-		if err := iprot.ReadFieldEnd(); err != nil {
-			return err
-		}
-	}
-	if err := iprot.ReadStructEnd(); err != nil {
-		return fmt.Errorf("%T read struct end error: %s", p, err)
-	}
-	return nil
-}
-
-func (p *TGetOperationStatusResp) ReadField1(iprot thrift.TProtocol) error {
-	p.Status = TStatus{}
-	if err := p.Status.Read(iprot); err != nil {
-		return fmt.Errorf("%T error reading struct: %s", p.Status, err)
-	}
-	return nil
-}
-
-func (p *TGetOperationStatusResp) ReadField2(iprot thrift.TProtocol) error {
-	if v, err := iprot.ReadI32(); err != nil {
-		return fmt.Errorf("error reading field 2: %s", err)
-	} else {
-		temp := TOperationState(v)
-		p.OperationState = &temp
-	}
-	return nil
-}
-
-func (p *TGetOperationStatusResp) Write(oprot thrift.TProtocol) error {
-	if err := oprot.WriteStructBegin("TGetOperationStatusResp"); err != nil {
-		return fmt.Errorf("%T write struct begin error: %s", p, err)
-	}
-	if err := p.writeField1(oprot); err != nil {
-		return err
-	}
-	if err := p.writeField2(oprot); err != nil {
-		return err
-	}
-	if err := oprot.WriteFieldStop(); err != nil {
-		return fmt.Errorf("write field stop error: %s", err)
-	}
-	if err := oprot.WriteStructEnd(); err != nil {
-		return fmt.Errorf("write struct stop error: %s", err)
-	}
-	return nil
-}
-
-func (p *TGetOperationStatusResp) writeField1(oprot thrift.TProtocol) (err error) {
-	if err := oprot.WriteFieldBegin("status", thrift.STRUCT, 1); err != nil {
-		return fmt.Errorf("%T write field begin error 1:status: %s", p, err)
-	}
-	if err := p.Status.Write(oprot); err != nil {
-		return fmt.Errorf("%T error writing struct: %s", p.Status, err)
-	}
-	if err := oprot.WriteFieldEnd(); err != nil {
-		return fmt.Errorf("%T write field end error 1:status: %s", p, err)
-	}
-	return err
-}
-
-func (p *TGetOperationStatusResp) writeField2(oprot thrift.TProtocol) (err error) {
-	if p.IsSetOperationState() {
-		if err := oprot.WriteFieldBegin("operationState", thrift.I32, 2); err != nil {
-			return fmt.Errorf("%T write field begin error 2:operationState: %s", p, err)
-		}
-		if err := oprot.WriteI32(int32(*p.OperationState)); err != nil {
-			return fmt.Errorf("%T.operationState (2) field write error: %s", p, err)
-		}
-		if err := oprot.WriteFieldEnd(); err != nil {
-			return fmt.Errorf("%T write field end error 2:operationState: %s", p, err)
-		}
-	}
-	return err
-}
-
-func (p *TGetOperationStatusResp) String() string {
-	if p == nil {
-		return "<nil>"
-	}
-	return fmt.Sprintf("TGetOperationStatusResp(%+v)", *p)
-}
-
 type TCancelOperationReq struct {
 	OperationHandle TOperationHandle `thrift:"operationHandle,1,required"`
 }
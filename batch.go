@@ -0,0 +1,62 @@
+package hivething
+
+import (
+	"github.com/derekgr/hivething/TCLIService"
+)
+
+// Rough in-memory overhead of a fetched value, before any string data: the
+// TColumnValue union, the pointer to its populated member, and the member.
+const valueOverheadBytes = 64
+
+// Returns the number of rows to ask for in the next fetch. With a memory
+// budget, this is as many rows as are estimated to fit in it, going by the
+// average size of the rows fetched so far; BatchSize is used until then.
+func (r *rowSet) batchSize() int64 {
+	budget := r.options.BatchMemoryBytes
+	if budget <= 0 || r.rowsFetched == 0 {
+		return r.options.BatchSize
+	}
+
+	rowBytes := r.bytesFetched / r.rowsFetched
+	if rowBytes < 1 {
+		rowBytes = 1
+	}
+
+	size := budget / rowBytes
+	if size < 1 {
+		size = 1
+	}
+
+	return size
+}
+
+// Add a fetched batch to the totals used to size later batches, and
+// report it to Metrics.
+func (r *rowSet) observeBatch(rows *tcliservice.TRowSet) {
+	if r.options.BatchMemoryBytes <= 0 && r.options.Metrics == nil {
+		return
+	}
+
+	var size int64
+	for _, row := range rows.Rows {
+		size += estimateRowBytes(row)
+	}
+	r.bytesFetched += size
+	r.rowsFetched += int64(len(rows.Rows))
+
+	if r.options.Metrics != nil {
+		r.options.Metrics.Fetched(len(rows.Rows), size)
+	}
+}
+
+// Returns an estimate of the memory a fetched row occupies.
+func estimateRowBytes(row *tcliservice.TRow) int64 {
+	size := int64(len(row.ColVals)) * valueOverheadBytes
+	for _, val := range row.ColVals {
+		if val != nil && val.StringVal.Value != nil {
+			size += int64(len(*val.StringVal.Value))
+		}
+	}
+
+	return size
+}
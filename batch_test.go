@@ -0,0 +1,62 @@
+package hivething
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/derekgr/hivething/TCLIService"
+)
+
+func stringRows(n int, width int) *tcliservice.TRowSet {
+	rows := tcliservice.NewTRowSet()
+	for i := 0; i < n; i++ {
+		s := strings.Repeat("x", width)
+		val := tcliservice.NewTColumnValue()
+		val.StringVal.Value = &s
+		rows.Rows = append(rows.Rows, &tcliservice.TRow{ColVals: []*tcliservice.TColumnValue{val}})
+	}
+	return rows
+}
+
+func TestBatchSizeWithoutBudget(t *testing.T) {
+	r := &rowSet{options: Options{BatchSize: 100}}
+	r.observeBatch(stringRows(100, 1000))
+
+	if size := r.batchSize(); size != 100 {
+		t.Errorf("Expected BatchSize 100 without a budget but got %d", size)
+	}
+}
+
+func TestBatchSizeFromBudget(t *testing.T) {
+	r := &rowSet{options: Options{BatchSize: 100, BatchMemoryBytes: 100000}}
+
+	if size := r.batchSize(); size != 100 {
+		t.Errorf("Expected the first batch to use BatchSize 100 but got %d", size)
+	}
+
+	// Wide rows shrink the batch to fit the budget.
+	r.observeBatch(stringRows(100, 1000-valueOverheadBytes))
+	if size := r.batchSize(); size != 100 {
+		t.Errorf("Expected 100 rows of 1000 bytes in a 100000 byte budget but got %d", size)
+	}
+
+	r = &rowSet{options: Options{BatchSize: 100, BatchMemoryBytes: 100000}}
+	r.observeBatch(stringRows(100, 10000-valueOverheadBytes))
+	if size := r.batchSize(); size != 10 {
+		t.Errorf("Expected 10 rows of 10000 bytes in a 100000 byte budget but got %d", size)
+	}
+
+	// Narrow rows grow it.
+	r = &rowSet{options: Options{BatchSize: 100, BatchMemoryBytes: 100000}}
+	r.observeBatch(stringRows(100, 100-valueOverheadBytes))
+	if size := r.batchSize(); size != 1000 {
+		t.Errorf("Expected 1000 rows of 100 bytes in a 100000 byte budget but got %d", size)
+	}
+
+	// A row bigger than the whole budget is still fetched.
+	r = &rowSet{options: Options{BatchSize: 100, BatchMemoryBytes: 100}}
+	r.observeBatch(stringRows(1, 1000))
+	if size := r.batchSize(); size != 1 {
+		t.Errorf("Expected a batch of at least 1 row but got %d", size)
+	}
+}
@@ -0,0 +1,276 @@
+package hivething
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// A NamedArg binds a value to a :name placeholder in a query. Construct
+// one with Named.
+type NamedArg struct {
+	Name  string
+	Value interface{}
+}
+
+// Returns a NamedArg binding value to the :name placeholder in a query,
+// e.g. db.Query("SELECT * FROM t WHERE id = :id", hivething.Named("id", 7)).
+func Named(name string, value interface{}) NamedArg {
+	return NamedArg{name, value}
+}
+
+// Hiveserver2 has no server side prepared statements, so placeholders are
+// substituted on the client with quoted and escaped HiveQL literals. ?
+// placeholders consume positional arguments in order, and :name
+// placeholders refer to NamedArgs. Placeholders inside string literals,
+// quoted identifiers and comments are left alone. A query with no args is
+// returned unchanged.
+func bind(query string, args []interface{}) (string, error) {
+	if len(args) == 0 {
+		return query, nil
+	}
+
+	named := make(map[string]interface{})
+	positional := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		if n, ok := arg.(NamedArg); ok {
+			named[n.Name] = n.Value
+		} else {
+			positional = append(positional, arg)
+		}
+	}
+
+	var (
+		buf  bytes.Buffer
+		used int
+	)
+
+	for i := 0; i < len(query); {
+		if j := skipNonCode(query, i); j > i {
+			buf.WriteString(query[i:j])
+			i = j
+			continue
+		}
+
+		var (
+			val  interface{}
+			next int
+		)
+
+		switch {
+		case query[i] == '?':
+			if used >= len(positional) {
+				return "", fmt.Errorf("Query has more ? placeholders than the %d positional arguments given", len(positional))
+			}
+			val = positional[used]
+			used++
+			next = i + 1
+		case isNamedPlaceholder(query, i):
+			next = i + 1
+			for next < len(query) && isIdentChar(query[next]) {
+				next++
+			}
+			name := query[i+1 : next]
+
+			var ok bool
+			if val, ok = named[name]; !ok {
+				return "", fmt.Errorf("No argument given for placeholder :%s", name)
+			}
+		default:
+			buf.WriteByte(query[i])
+			i++
+			continue
+		}
+
+		lit, err := literal(val)
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString(lit)
+		i = next
+	}
+
+	if used < len(positional) {
+		return "", fmt.Errorf("Query has %d ? placeholders but %d positional arguments were given", used, len(positional))
+	}
+
+	return buf.String(), nil
+}
+
+// Returns the index just past the string literal, quoted identifier or
+// comment starting at query[i], or i if there isn't one there. Unterminated
+// literals and comments run to the end of the query.
+func skipNonCode(query string, i int) int {
+	switch c := query[i]; {
+	case c == '\'' || c == '"':
+		for j := i + 1; j < len(query); j++ {
+			switch query[j] {
+			case '\\':
+				j++
+			case c:
+				return j + 1
+			}
+		}
+		return len(query)
+	case c == '`':
+		for j := i + 1; j < len(query); j++ {
+			if query[j] == '`' {
+				// A doubled backtick is an escaped backtick.
+				if j+1 < len(query) && query[j+1] == '`' {
+					j++
+					continue
+				}
+				return j + 1
+			}
+		}
+		return len(query)
+	case c == '-' && i+1 < len(query) && query[i+1] == '-':
+		for j := i + 2; j < len(query); j++ {
+			if query[j] == '\n' {
+				return j + 1
+			}
+		}
+		return len(query)
+	case c == '/' && i+1 < len(query) && query[i+1] == '*':
+		for j := i + 2; j+1 < len(query); j++ {
+			if query[j] == '*' && query[j+1] == '/' {
+				return j + 2
+			}
+		}
+		return len(query)
+	}
+
+	return i
+}
+
+// A :name placeholder must not follow an identifier, so that
+// ${hivevar:name} style variable references aren't mistaken for one.
+func isNamedPlaceholder(query string, i int) bool {
+	if query[i] != ':' || i+1 >= len(query) {
+		return false
+	}
+
+	if i > 0 && (isIdentChar(query[i-1]) || query[i-1] == ':') {
+		return false
+	}
+
+	next := query[i+1]
+	return next == '_' || ('a' <= next && next <= 'z') || ('A' <= next && next <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// Converts a Go value into a HiveQL literal. Slices other than []byte
+// become a parenthesized list, for use with IN (...).
+func literal(val interface{}) (string, error) {
+	switch v := val.(type) {
+	case nil:
+		return "NULL", nil
+	case string:
+		return quoteString(v), nil
+	case []byte:
+		return "unhex('" + hex.EncodeToString(v) + "')", nil
+	case bool:
+		if v {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case time.Time:
+		return "CAST('" + v.Format("2006-01-02 15:04:05.999999999") + "' AS TIMESTAMP)", nil
+	}
+
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return "NULL", nil
+		}
+		return literal(rv.Elem().Interface())
+	case reflect.String:
+		return quoteString(rv.String()), nil
+	case reflect.Bool:
+		return literal(rv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return signed(strconv.FormatInt(rv.Int(), 10)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		switch {
+		case math.IsNaN(f):
+			return "CAST('NaN' AS DOUBLE)", nil
+		case math.IsInf(f, 1):
+			return "CAST('Infinity' AS DOUBLE)", nil
+		case math.IsInf(f, -1):
+			return "CAST('-Infinity' AS DOUBLE)", nil
+		}
+		return signed(strconv.FormatFloat(f, 'g', -1, rv.Type().Bits())), nil
+	case reflect.Slice, reflect.Array:
+		if rv.Len() == 0 {
+			return "", fmt.Errorf("Can't bind empty %T as a list", val)
+		}
+
+		var buf bytes.Buffer
+		buf.WriteByte('(')
+		for i := 0; i < rv.Len(); i++ {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			lit, err := literal(rv.Index(i).Interface())
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(lit)
+		}
+		buf.WriteByte(')')
+		return buf.String(), nil
+	}
+
+	return "", fmt.Errorf("Can't bind value of type %T", val)
+}
+
+// Negative numbers are parenthesized, since a placeholder following a
+// minus sign would otherwise begin a -- comment.
+func signed(num string) string {
+	if len(num) > 0 && num[0] == '-' {
+		return "(" + num + ")"
+	}
+	return num
+}
+
+// Quotes s as a single quoted HiveQL string, backslash escaping anything
+// that could end the literal early or be mangled by the lexer.
+func quoteString(s string) string {
+	var buf bytes.Buffer
+	buf.WriteByte('\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\'':
+			buf.WriteString(`\'`)
+		case '"':
+			buf.WriteString(`\"`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case 0:
+			// Not \0, which the lexer would read as an octal escape if
+			// digits followed.
+			buf.WriteString(`\000`)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('\'')
+	return buf.String()
+}
@@ -0,0 +1,259 @@
+package hivething
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBind(t *testing.T) {
+	when := time.Date(2014, 6, 1, 12, 30, 0, 500, time.UTC)
+	var nilInt *int
+
+	cases := []struct {
+		query    string
+		args     []interface{}
+		expected string
+	}{
+		{"SELECT '?' FROM t", nil, "SELECT '?' FROM t"},
+		{"SELECT * FROM t WHERE a = ? AND b = ?", []interface{}{1, "x"}, "SELECT * FROM t WHERE a = 1 AND b = 'x'"},
+		{"SELECT * FROM t WHERE a = ?", []interface{}{"it's"}, `SELECT * FROM t WHERE a = 'it\'s'`},
+		{"SELECT * FROM t WHERE a = ?", []interface{}{`a\b"c` + "\n"}, `SELECT * FROM t WHERE a = 'a\\b\"c\n'`},
+		{"SELECT 1-?", []interface{}{-5}, "SELECT 1-(-5)"},
+		{"SELECT ?, ?", []interface{}{1.5, math.Inf(1)}, "SELECT 1.5, CAST('Infinity' AS DOUBLE)"},
+		{"SELECT ?, ?", []interface{}{true, nil}, "SELECT TRUE, NULL"},
+		{"SELECT ?, ?", []interface{}{nilInt, []byte("hi")}, "SELECT NULL, unhex('6869')"},
+		{"SELECT ?", []interface{}{when}, "SELECT CAST('2014-06-01 12:30:00.0000005' AS TIMESTAMP)"},
+		{"SELECT * FROM t WHERE id IN ?", []interface{}{[]int{1, 2, 3}}, "SELECT * FROM t WHERE id IN (1, 2, 3)"},
+		{"SELECT * FROM t WHERE a = :a OR b = :a", []interface{}{Named("a", "x")}, "SELECT * FROM t WHERE a = 'x' OR b = 'x'"},
+		{"SELECT `?`, \"?\", :a -- ?\n/* :a */", []interface{}{Named("a", 1)}, "SELECT `?`, \"?\", 1 -- ?\n/* :a */"},
+		{"SELECT * FROM ${hivevar:tbl} WHERE a = ?", []interface{}{1}, "SELECT * FROM ${hivevar:tbl} WHERE a = 1"},
+	}
+
+	for _, c := range cases {
+		bound, err := bind(c.query, c.args)
+		if err != nil {
+			t.Errorf("bind(%q) error: %v", c.query, err)
+			continue
+		}
+
+		if bound != c.expected {
+			t.Errorf("bind(%q): expected %q but got %q", c.query, c.expected, bound)
+		}
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	cases := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"SELECT ?, ?", []interface{}{1}},
+		{"SELECT ?", []interface{}{1, 2}},
+		{"SELECT :missing", []interface{}{Named("other", 1)}},
+		{"SELECT * FROM t WHERE id IN ?", []interface{}{[]int{}}},
+		{"SELECT ?", []interface{}{struct{}{}}},
+	}
+
+	for _, c := range cases {
+		if bound, err := bind(c.query, c.args); err == nil {
+			t.Errorf("bind(%q, %v): expected error but got %q", c.query, c.args, bound)
+		}
+	}
+}
+
+// Empties every string literal in a statement, returning the
+// stripped statement and the unescaped literal values, the way the hive
+// lexer and BaseSemanticAnalyzer.unescapeSQLString would see them. It scans
+// a character at a time following HiveLexer's StringLiteral and COMMENT
+// rules, rather than with skipNonCode, so that it checks bind's scanning
+// too. An unterminated literal is left in the statement as is.
+func stripLiterals(statement string) (string, []string) {
+	const (
+		inCode = iota
+		inString
+		inComment
+	)
+
+	var (
+		stripped strings.Builder
+		literal  strings.Builder
+		literals []string
+		state    = inCode
+		quote    byte
+		start    int
+	)
+
+	for i := 0; i < len(statement); i++ {
+		c := statement[i]
+		switch state {
+		case inCode:
+			switch {
+			case c == '\'' || c == '"':
+				state, quote, start = inString, c, i
+				literal.Reset()
+			case c == '-' && strings.HasPrefix(statement[i:], "--"):
+				state = inComment
+				stripped.WriteByte(c)
+			default:
+				stripped.WriteByte(c)
+			}
+		case inString:
+			switch {
+			case c == '\\' && i+1 < len(statement):
+				literal.WriteByte(c)
+				i++
+				literal.WriteByte(statement[i])
+			case c == quote:
+				state = inCode
+				stripped.WriteString("''")
+				literals = append(literals, unescapeHive(literal.String()))
+			default:
+				literal.WriteByte(c)
+			}
+		case inComment:
+			stripped.WriteByte(c)
+			if c == '\n' {
+				state = inCode
+			}
+		}
+	}
+
+	if state == inString {
+		stripped.WriteString(statement[start:])
+	}
+
+	return stripped.String(), literals
+}
+
+// As unescapeSQLString does, including its \uXXXX and octal escapes, whose
+// first digit must be 0 or 1.
+func unescapeHive(s string) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			out.WriteByte(s[i])
+			continue
+		}
+
+		if s[i+1] == 'u' && i+5 < len(s) {
+			code := 0
+			for _, c := range s[i+2 : i+6] {
+				code = code<<4 + hexDigit(c)
+			}
+			out.WriteRune(rune(code & 0xffff))
+			i += 5
+			continue
+		}
+
+		if i+3 < len(s) && isOctal(s[i+1], '1') && isOctal(s[i+2], '7') && isOctal(s[i+3], '7') {
+			out.WriteByte((s[i+1]-'0')*64 + (s[i+2]-'0')*8 + s[i+3] - '0')
+			i += 3
+			continue
+		}
+
+		i++
+		switch s[i] {
+		case '0':
+			out.WriteByte(0)
+		case 'n':
+			out.WriteByte('\n')
+		case 'r':
+			out.WriteByte('\r')
+		case 't':
+			out.WriteByte('\t')
+		case 'b':
+			out.WriteByte('\b')
+		case 'Z':
+			out.WriteByte(26)
+		case '%', '_':
+			out.WriteByte('\\')
+			out.WriteByte(s[i])
+		default:
+			out.WriteByte(s[i])
+		}
+	}
+
+	return out.String()
+}
+
+func isOctal(c, max byte) bool {
+	return '0' <= c && c <= max
+}
+
+// Like Java's Character.digit(c, 16), -1 for anything but a hex digit.
+func hexDigit(c rune) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'f':
+		return int(c-'a') + 10
+	case 'A' <= c && c <= 'F':
+		return int(c-'A') + 10
+	}
+	return -1
+}
+
+func TestStripLiterals(t *testing.T) {
+	cases := []struct {
+		statement string
+		stripped  string
+		literals  []string
+	}{
+		{`SELECT 'a', "b" -- 'c'`, `SELECT '', '' -- 'c'`, []string{"a", "b"}},
+		{`SELECT 'it\'s', 'x\\' FROM t`, `SELECT '', '' FROM t`, []string{"it's", `x\`}},
+		{"SELECT 1 -- 'x\n, 'y'", "SELECT 1 -- 'x\n, ''", []string{"y"}},
+		{`SELECT 'a\n\%'`, `SELECT ''`, []string{"a\n\\%"}},
+		{`SELECT '\012\0', '\u0041\289'`, `SELECT '', ''`, []string{"\n\x00", "A289"}},
+		{`SELECT 'open`, `SELECT 'open`, nil},
+	}
+
+	for _, c := range cases {
+		stripped, literals := stripLiterals(c.statement)
+		if stripped != c.stripped || !reflect.DeepEqual(literals, c.literals) {
+			t.Errorf("stripLiterals(%q): expected %q, %q but got %q, %q", c.statement, c.stripped, c.literals, stripped, literals)
+		}
+	}
+}
+
+func FuzzBindString(f *testing.F) {
+	for _, seed := range []string{"", "'", `\`, `\'`, "'; DROP TABLE foo; --", "*/ ?", "\x00\n\r\t", "\x0012", "`\"", `\%_`} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, value string) {
+		bound, err := bind("SELECT * FROM t WHERE a = ? AND b = :b -- ?", []interface{}{value, Named("b", value)})
+		if err != nil {
+			t.Fatalf("bind error: %v", err)
+		}
+
+		stripped, literals := stripLiterals(bound)
+		if stripped != "SELECT * FROM t WHERE a = '' AND b = '' -- ?" {
+			t.Fatalf("Value %q escaped its literal: %s", value, bound)
+		}
+
+		for _, lit := range literals {
+			if lit != value {
+				t.Fatalf("Value %q round tripped as %q", value, lit)
+			}
+		}
+	})
+}
+
+func FuzzBindNumber(f *testing.F) {
+	f.Add(int64(-1), -0.5)
+	f.Add(int64(math.MinInt64), math.Inf(-1))
+
+	f.Fuzz(func(t *testing.T, i int64, d float64) {
+		bound, err := bind("SELECT 1-?, 1-?", []interface{}{i, d})
+		if err != nil {
+			t.Fatalf("bind error: %v", err)
+		}
+
+		if strings.Contains(bound, "--") {
+			t.Fatalf("Bound numbers started a comment: %s", bound)
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package hivething
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// A Client issues queries and metadata calls on a hive session. It is
+// implemented by *Connection, and by hivetest.MockClient so that code
+// depending on hivething can be tested without a server.
+type Client interface {
+	Query(query string, args ...interface{}) (RowSet, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (RowSet, error)
+	QueryWithConfig(query string, config map[string]string, args ...interface{}) (RowSet, error)
+	GetSchemas(schemaPattern string) (RowSet, error)
+	GetTables(schemaPattern, tablePattern string) (RowSet, error)
+	GetColumns(schemaPattern, tablePattern, columnPattern string) (RowSet, error)
+	GetFunctions(schemaPattern, functionPattern string) (RowSet, error)
+	Reattach(handle []byte) (RowSet, error)
+	Close() error
+}
+
+var _ Client = (*Connection)(nil)
+
+type staticRowSet struct {
+	columns []string
+	rows    [][]interface{}
+	offset  int
+}
+
+// Returns a completed, successful RowSet over rows already in memory, as
+// for canned results in tests. Each row's values should have the types a
+// hive RowSet produces: string, bool, int32, int64, float64 or nil.
+// It has no Handle.
+func NewStaticRowSet(columns []string, rows [][]interface{}) RowSet {
+	return &staticRowSet{columns, rows, 0}
+}
+
+func (r *staticRowSet) Handle() ([]byte, error) {
+	return nil, errors.New("A static RowSet has no operation handle")
+}
+
+func (r *staticRowSet) Columns() []string {
+	return r.columns
+}
+
+// Static rows are already complete, so there's nothing to cancel.
+func (r *staticRowSet) Cancel() error {
+	return nil
+}
+
+func (r *staticRowSet) Next() bool {
+	if r.offset >= len(r.rows) {
+		return false
+	}
+
+	r.offset++
+	return true
+}
+
+// Iterating over static rows can't fail.
+func (r *staticRowSet) Err() error {
+	return nil
+}
+
+func (r *staticRowSet) Scan(dest ...interface{}) error {
+	if r.offset == 0 {
+		return scanRow(nil, dest)
+	}
+
+	return scanRow(r.rows[r.offset-1], dest)
+}
+
+func (r *staticRowSet) Poll() (*Status, error) {
+	now := time.Now()
+	status := &Status{State: StateFinished, At: now, SubmittedAt: now, StartedAt: now, CompletedAt: now}
+	status.Progress = &Progress{History: []Status{*status}, Percent: 100}
+	return status, nil
+}
+
+func (r *staticRowSet) Wait() (*Status, error) {
+	return r.Poll()
+}
+
+func (r *staticRowSet) WaitWithProgress(report func(Progress)) (*Status, error) {
+	status, _ := r.Poll()
+	if report != nil {
+		report(*status.Progress)
+	}
+	return status, nil
+}
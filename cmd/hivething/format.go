@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/derekgr/hivething"
+	"github.com/derekgr/hivething/export"
+)
+
+// Writes rows in an output format, returning the number written.
+type formatter func(w io.Writer, rows hivething.RowSet) (int64, error)
+
+var formats = map[string]formatter{
+	"table":    writeTable,
+	"vertical": writeVertical,
+	"csv": func(w io.Writer, rows hivething.RowSet) (int64, error) {
+		return export.WriteCSV(w, rows, export.Options{Null: "NULL"})
+	},
+	"tsv": func(w io.Writer, rows hivething.RowSet) (int64, error) {
+		return export.WriteTSV(w, rows, export.Options{Null: "NULL"})
+	},
+	"json": func(w io.Writer, rows hivething.RowSet) (int64, error) {
+		return export.WriteJSONLines(w, rows, export.Options{})
+	},
+}
+
+// Returns a value as displayed in a table or vertical output, on one line.
+func display(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return strings.NewReplacer("\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Reads the remaining rows as displayed strings.
+func readDisplayed(rows hivething.RowSet, columns int) ([][]string, error) {
+	vals := make([]interface{}, columns)
+	dest := make([]interface{}, columns)
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+
+	var table [][]string
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		row := make([]string, columns)
+		for i, val := range vals {
+			row[i] = display(val)
+		}
+		table = append(table, row)
+	}
+
+	return table, rows.Err()
+}
+
+// Writes rows as a table with borders, as beeline does. The rows are read
+// into memory first, to size the columns.
+func writeTable(w io.Writer, rows hivething.RowSet) (int64, error) {
+	columns := rows.Columns()
+	table, err := readDisplayed(rows, len(columns))
+	if err != nil {
+		return 0, err
+	}
+
+	widths := make([]int, len(columns))
+	for i, name := range columns {
+		widths[i] = utf8.RuneCountInString(name)
+	}
+	for _, row := range table {
+		for i, val := range row {
+			if n := utf8.RuneCountInString(val); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	var b strings.Builder
+	border := func() {
+		for _, width := range widths {
+			b.WriteString("+")
+			b.WriteString(strings.Repeat("-", width+2))
+		}
+		b.WriteString("+\n")
+	}
+	line := func(vals []string) {
+		for i, val := range vals {
+			b.WriteString("| ")
+			b.WriteString(val)
+			b.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(val)+1))
+		}
+		b.WriteString("|\n")
+	}
+
+	border()
+	line(columns)
+	border()
+	for _, row := range table {
+		line(row)
+	}
+	border()
+
+	_, err = io.WriteString(w, b.String())
+	return int64(len(table)), err
+}
+
+// Writes each row as a block of lines, a column name and value on each,
+// which suits wide rows.
+func writeVertical(w io.Writer, rows hivething.RowSet) (int64, error) {
+	columns := rows.Columns()
+
+	width := 0
+	for _, name := range columns {
+		if n := utf8.RuneCountInString(name); n > width {
+			width = n
+		}
+	}
+
+	vals := make([]interface{}, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+
+	var n int64
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, err
+		}
+
+		var b strings.Builder
+		if n > 0 {
+			b.WriteString("\n")
+		}
+		for i, name := range columns {
+			b.WriteString(name)
+			b.WriteString(strings.Repeat(" ", width-utf8.RuneCountInString(name)+2))
+			b.WriteString(display(vals[i]))
+			b.WriteString("\n")
+		}
+
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, rows.Err()
+}
@@ -0,0 +1,173 @@
+// Command hivething is a hive shell in the manner of beeline, without the
+// JVM. With no statements given by -e or -f, and a terminal on stdin, it
+// reads statements interactively, with history and line editing;
+// otherwise it runs the statements given, or read from stdin, and exits.
+//
+//	hivething -h hive.example.com:10000 -e 'select count(*) from events'
+//	hivething -h hive.example.com:10000 -f report.hql -o csv > report.csv
+//
+// Statements end with a semicolon and may span lines. Lines starting with
+// ! are shell commands; !help lists them. Interrupting a running query
+// cancels it on the server, and interrupting while its results print stops
+// fetching them.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/chzyer/readline"
+	"github.com/derekgr/hivething"
+)
+
+func main() {
+	var (
+		host    = flag.String("h", "localhost:10000", "hiveserver2 `host:port` to connect to")
+		execute = flag.String("e", "", "run the given `statements` and exit")
+		file    = flag.String("f", "", "run the statements in `file` and exit")
+		format  = flag.String("o", "table", "output `format`: "+strings.Join(formatNames(), ", "))
+		force   = flag.Bool("force", false, "keep running statements after one fails")
+	)
+	flag.Parse()
+
+	if _, ok := formats[*format]; !ok {
+		fmt.Fprintf(os.Stderr, "Unknown output format %q\n", *format)
+		os.Exit(2)
+	}
+
+	s := &shell{
+		options:    hivething.DefaultOptions,
+		format:     *format,
+		out:        os.Stdout,
+		errOut:     os.Stderr,
+		progress:   readline.IsTerminal(int(os.Stderr.Fd())),
+		force:      *force,
+		interrupts: make(chan os.Signal, 1),
+	}
+
+	interactive := *execute == "" && *file == "" && readline.IsTerminal(int(os.Stdin.Fd()))
+	if err := s.connect(*host); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if !interactive {
+			os.Exit(1)
+		}
+	}
+	defer s.close()
+
+	var err error
+	switch {
+	case *execute != "":
+		err = s.runScript(strings.NewReader(*execute))
+	case *file != "":
+		var f *os.File
+		if f, err = os.Open(*file); err == nil {
+			err = s.runScript(f)
+			f.Close()
+		}
+	case interactive:
+		err = s.repl()
+	default:
+		err = s.runScript(os.Stdin)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		s.close()
+		os.Exit(1)
+	}
+}
+
+// Read and run statements and commands from the terminal until EOF or
+// !quit, keeping history in ~/.hivething_history.
+func (s *shell) repl() error {
+	config := &readline.Config{
+		Prompt:                 "hive> ",
+		DisableAutoSaveHistory: true,
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		config.HistoryFile = filepath.Join(home, ".hivething_history")
+	}
+
+	rl, err := readline.NewEx(config)
+	if err != nil {
+		return err
+	}
+	defer rl.Close()
+
+	var entry []string
+	for {
+		line, err := rl.Readline()
+		if err == readline.ErrInterrupt {
+			// Discard the statement being typed.
+			s.pending = ""
+			entry = nil
+			rl.SetPrompt("hive> ")
+			continue
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		entry = append(entry, line)
+		quit, err := s.feed(line)
+		if err != nil {
+			fmt.Fprintln(s.errOut, err)
+		}
+
+		if s.pending == "" {
+			if text := strings.TrimSpace(strings.Join(entry, " ")); text != "" {
+				rl.SaveHistory(text)
+			}
+			entry = nil
+			rl.SetPrompt("hive> ")
+		} else {
+			rl.SetPrompt("    > ")
+		}
+
+		if quit {
+			return nil
+		}
+	}
+}
+
+// Run statements and commands from r, stopping at the first that fails
+// unless -force was given. A final statement needn't end with a semicolon.
+func (s *shell) runScript(r io.Reader) error {
+	text, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range strings.Split(string(text), "\n") {
+		quit, err := s.feed(line)
+		if err != nil && !s.force {
+			return err
+		}
+		if err != nil {
+			fmt.Fprintln(s.errOut, err)
+		}
+		if quit {
+			return nil
+		}
+	}
+
+	return s.flush()
+}
+
+func formatNames() []string {
+	var names []string
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/derekgr/hivething"
+)
+
+const help = `Statements end with a semicolon, and may span lines. Commands:
+  !connect host:port     connect to another server
+  !tables [pattern]      list tables, optionally matching schema.table pattern
+  !describe [db.]table   describe a table's columns, in the current database by default
+  !format name           set the output format: %s
+  !help                  show this help
+  !quit                  exit
+`
+
+type shell struct {
+	host    string
+	options hivething.Options
+	db      *hivething.Connection
+	format  string
+	out     io.Writer
+	errOut  io.Writer
+	// Show the state of running queries on errOut.
+	progress bool
+	force    bool
+	// Receives interrupts while a statement runs, to cancel it or stop
+	// printing its results; otherwise they exit as usual.
+	interrupts chan os.Signal
+	// Text of an incomplete statement.
+	pending string
+}
+
+var (
+	errCancelled   = errors.New("Query cancelled")
+	errInterrupted = errors.New("Interrupted; results are incomplete")
+)
+
+func (s *shell) connect(host string) error {
+	db, err := hivething.Connect(host, s.options)
+	if err != nil {
+		return fmt.Errorf("Error connecting to %s: %v", host, err)
+	}
+
+	s.close()
+	s.db, s.host = db, host
+	fmt.Fprintf(s.errOut, "Connected to %s\n", host)
+	return nil
+}
+
+func (s *shell) close() {
+	if s.db != nil {
+		s.db.Close()
+		s.db = nil
+	}
+}
+
+// Handle a line of input: a command, or part of a statement, running any
+// statements it completes. Returns true if the shell should exit.
+func (s *shell) feed(line string) (bool, error) {
+	if s.pending == "" && strings.HasPrefix(strings.TrimSpace(line), "!") {
+		return s.command(strings.Fields(strings.TrimSpace(line)[1:]))
+	}
+
+	// Wait for a terminating semicolon before running anything.
+	s.pending += line + "\n"
+	if _, rest := hivething.SplitScript(s.pending); rest != "" {
+		return false, nil
+	}
+
+	return false, s.flush()
+}
+
+// Run the pending statements, including any without a terminating
+// semicolon.
+func (s *shell) flush() error {
+	statements, rest := hivething.SplitScript(s.pending)
+	if rest != "" {
+		statements = append(statements, rest)
+	}
+	s.pending = ""
+
+	for _, statement := range statements {
+		if err := s.execute(statement); err != nil {
+			if !s.force {
+				return err
+			}
+			fmt.Fprintln(s.errOut, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *shell) command(args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, errors.New("Missing command; try !help")
+	}
+
+	switch args[0] {
+	case "quit", "exit", "q":
+		return true, nil
+	case "help":
+		fmt.Fprintf(s.errOut, help, strings.Join(formatNames(), ", "))
+		return false, nil
+	case "connect":
+		if len(args) != 2 {
+			return false, errors.New("Usage: !connect host:port")
+		}
+		return false, s.connect(args[1])
+	case "format":
+		if len(args) != 2 {
+			return false, fmt.Errorf("Output format is %s", s.format)
+		}
+		if _, ok := formats[args[1]]; !ok {
+			return false, fmt.Errorf("Unknown output format %q", args[1])
+		}
+		s.format = args[1]
+		return false, nil
+	case "tables":
+		if len(args) > 2 {
+			return false, errors.New("Usage: !tables [pattern]")
+		}
+		schema, table := "", "%"
+		if len(args) == 2 {
+			schema, table = splitName(args[1])
+		}
+		return false, s.metadata(func() (hivething.RowSet, error) {
+			return s.db.GetTables(schema, table)
+		}, "TABLE_SCHEM", "TABLE_NAME", "TABLE_TYPE", "REMARKS")
+	case "describe":
+		if len(args) != 2 {
+			return false, errors.New("Usage: !describe [db.]table")
+		}
+		schema, table := splitName(args[1])
+		if schema == "" {
+			// An empty schema would match tables of the name in every
+			// database.
+			var err error
+			if schema, err = s.currentDatabase(); err != nil {
+				return false, err
+			}
+		}
+		return false, s.metadata(func() (hivething.RowSet, error) {
+			return s.db.GetColumns(schema, table, "%")
+		}, "COLUMN_NAME", "TYPE_NAME", "REMARKS")
+	default:
+		return false, fmt.Errorf("Unknown command !%s; try !help", args[0])
+	}
+}
+
+// Splits a schema.table name, for metadata calls.
+func splitName(name string) (schema, table string) {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+// Returns the session's current database, as set by USE.
+func (s *shell) currentDatabase() (string, error) {
+	if s.db == nil {
+		return "", errors.New("Not connected; use !connect host:port")
+	}
+
+	rows, err := s.db.Query("SELECT current_database()")
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	if _, err := rows.Wait(); err != nil {
+		return "", err
+	}
+
+	var name string
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return "", errors.New("Error finding the current database: no rows returned")
+	}
+	if err := rows.Scan(&name); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+// Run a metadata call, and print the given columns of its results, those
+// of interest from the many that JDBC calls for.
+func (s *shell) metadata(call func() (hivething.RowSet, error), columns ...string) error {
+	if s.db == nil {
+		return errors.New("Not connected; use !connect host:port")
+	}
+
+	start := time.Now()
+	rows, err := call()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if _, err := rows.Wait(); err != nil {
+		return err
+	}
+
+	var indexes []int
+	var names []string
+	for i, name := range rows.Columns() {
+		for _, wanted := range columns {
+			if strings.EqualFold(name, wanted) {
+				indexes = append(indexes, i)
+				names = append(names, name)
+			}
+		}
+	}
+
+	vals := make([]interface{}, len(rows.Columns()))
+	dest := make([]interface{}, len(vals))
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+
+	var selected [][]interface{}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+
+		row := make([]interface{}, len(indexes))
+		for i, index := range indexes {
+			row[i] = vals[index]
+		}
+		selected = append(selected, row)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return s.print(hivething.NewStaticRowSet(names, selected), start)
+}
+
+// Run a statement, showing its progress and printing its results.
+func (s *shell) execute(statement string) error {
+	if s.db == nil {
+		return errors.New("Not connected; use !connect host:port")
+	}
+
+	// Discard interrupts left from a previous statement, so that only those
+	// from once this one starts cancel it.
+	for len(s.interrupts) > 0 {
+		<-s.interrupts
+	}
+	signal.Notify(s.interrupts, os.Interrupt)
+	defer signal.Stop(s.interrupts)
+
+	start := time.Now()
+	rows, err := s.db.Query(statement)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if err := s.wait(rows, start); err != nil {
+		return err
+	}
+
+	return s.print(&interruptibleRows{RowSet: rows, interrupts: s.interrupts}, start)
+}
+
+// A RowSet that ends early once interrupted, so that an interrupt stops
+// fetching and printing a large result; closing it closes the operation.
+type interruptibleRows struct {
+	hivething.RowSet
+	interrupts  <-chan os.Signal
+	interrupted bool
+}
+
+func (r *interruptibleRows) Next() bool {
+	select {
+	case <-r.interrupts:
+		r.interrupted = true
+	default:
+	}
+
+	return !r.interrupted && r.RowSet.Next()
+}
+
+// Wait for the operation to complete, showing its state if s.progress is
+// set, and cancelling it if interrupted. An interrupt while cancelling
+// exits.
+func (s *shell) wait(rows hivething.RowSet, start time.Time) error {
+	var (
+		// Serializes output, and guards the rest.
+		mu        sync.Mutex
+		done      bool
+		cancelled bool
+		cancelErr error
+	)
+
+	finished := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-finished:
+				return
+			case <-s.interrupts:
+			}
+
+			mu.Lock()
+			if done {
+				mu.Unlock()
+				return
+			}
+			s.clearProgress()
+			if cancelled {
+				fmt.Fprintln(s.errOut, "Interrupted again; exiting without waiting for cancellation")
+				os.Exit(130)
+			}
+			cancelled = true
+			fmt.Fprintln(s.errOut, "Cancelling query...")
+			cancelErr = rows.Cancel()
+			mu.Unlock()
+		}
+	}()
+
+	// Fetches result set metadata once the query succeeds, or reports why
+	// it failed.
+	_, err := rows.WaitWithProgress(func(progress hivething.Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		if s.progress && !cancelled && len(progress.History) > 0 {
+			state := progress.History[len(progress.History)-1]
+			if progress.Percent >= 0 {
+				fmt.Fprintf(s.errOut, "\r%s %.0f%% %.1fs ", state, progress.Percent, time.Since(start).Seconds())
+			} else {
+				fmt.Fprintf(s.errOut, "\r%s %.1fs ", state, time.Since(start).Seconds())
+			}
+		}
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	done = true
+	close(finished)
+	s.clearProgress()
+
+	switch {
+	case cancelErr != nil:
+		return cancelErr
+	case cancelled:
+		return errCancelled
+	}
+	return err
+}
+
+func (s *shell) clearProgress() {
+	if s.progress {
+		fmt.Fprint(s.errOut, "\r\033[K")
+	}
+}
+
+// Print rows in the current format, followed by a summary on errOut.
+func (s *shell) print(rows hivething.RowSet, start time.Time) error {
+	if len(rows.Columns()) == 0 {
+		fmt.Fprintf(s.errOut, "No rows affected (%.3f seconds)\n", time.Since(start).Seconds())
+		return nil
+	}
+
+	n, err := formats[s.format](s.out, rows)
+	if err != nil {
+		return err
+	}
+	if r, ok := rows.(*interruptibleRows); ok && r.interrupted {
+		return errInterrupted
+	}
+
+	plural := "s"
+	if n == 1 {
+		plural = ""
+	}
+	fmt.Fprintf(s.errOut, "%d row%s selected (%.3f seconds)\n", n, plural, time.Since(start).Seconds())
+	return nil
+}
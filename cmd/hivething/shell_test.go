@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/derekgr/hivething"
+	"github.com/derekgr/hivething/TCLIService"
+	"github.com/derekgr/hivething/hivetest"
+)
+
+func newShell(t *testing.T) (*shell, *hivetest.Server, *bytes.Buffer, *bytes.Buffer, chan os.Signal) {
+	server, err := hivetest.NewServer()
+	if err != nil {
+		t.Fatalf("Can't start fake server: %v", err)
+	}
+
+	server.Handle("select * from foo").
+		Column("foo.id", tcliservice.TTypeId_INT_TYPE).
+		Column("foo.name", tcliservice.TTypeId_STRING_TYPE).
+		Row(int32(1), "foo").
+		Row(int32(2), nil).
+		RunFor(2)
+
+	var out, errOut bytes.Buffer
+	interrupts := make(chan os.Signal, 1)
+	s := &shell{
+		options:    hivething.Options{BatchSize: 10, PollStrategy: hivething.FixedInterval(time.Millisecond)},
+		format:     "table",
+		out:        &out,
+		errOut:     &errOut,
+		interrupts: interrupts,
+	}
+
+	if err := s.connect(server.Addr()); err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+
+	return s, server, &out, &errOut, interrupts
+}
+
+func TestRunScriptTable(t *testing.T) {
+	s, server, out, errOut, _ := newShell(t)
+	defer server.Close()
+	defer s.close()
+
+	// The statement isn't complete until the line with its semicolon.
+	if err := s.runScript(strings.NewReader("select * from foo\n;\n")); err != nil {
+		t.Fatalf("runScript error: %v", err)
+	}
+
+	executed := server.Executed()
+	if len(executed) != 1 || executed[0].Statement != "select * from foo" {
+		t.Errorf("Expected the multi-line statement to be executed once but got %v", executed)
+	}
+
+	expected := `+--------+----------+
+| foo.id | foo.name |
++--------+----------+
+| 1      | foo      |
+| 2      | NULL     |
++--------+----------+
+`
+	if out.String() != expected {
+		t.Errorf("Expected table\n%s\nbut got\n%s", expected, out.String())
+	}
+
+	if !strings.Contains(errOut.String(), "2 rows selected") {
+		t.Errorf("Expected a summary of rows selected but got %q", errOut.String())
+	}
+}
+
+func TestFormats(t *testing.T) {
+	expected := map[string]string{
+		"vertical": "foo.id    1\nfoo.name  foo\n\nfoo.id    2\nfoo.name  NULL\n",
+		"csv":      "foo.id,foo.name\n1,foo\n2,NULL\n",
+		"tsv":      "foo.id\tfoo.name\n1\tfoo\n2\tNULL\n",
+		"json":     `{"foo.id":1,"foo.name":"foo"}` + "\n" + `{"foo.id":2,"foo.name":null}` + "\n",
+	}
+
+	for format, want := range expected {
+		s, server, out, _, _ := newShell(t)
+
+		// A final statement needn't end with a semicolon.
+		if err := s.runScript(strings.NewReader("!format " + format + "\nselect * from foo")); err != nil {
+			t.Errorf("runScript error in %s format: %v", format, err)
+		}
+		if out.String() != want {
+			t.Errorf("Expected %s output\n%s\nbut got\n%s", format, want, out.String())
+		}
+
+		s.close()
+		server.Close()
+	}
+}
+
+func TestRunScriptStopsOnError(t *testing.T) {
+	s, server, _, _, _ := newShell(t)
+	defer server.Close()
+	defer s.close()
+
+	server.Handle("select oops").FailExecute("ParseException")
+	if err := s.runScript(strings.NewReader("select oops;\nselect * from foo;\n")); err == nil {
+		t.Error("Expected a failing statement to fail the script")
+	}
+	if n := len(server.Executed()); n != 1 {
+		t.Errorf("Expected the script to stop after the failure, but %d statements were executed", n)
+	}
+
+	s.force = true
+	if err := s.runScript(strings.NewReader("select oops;\nselect * from foo;\n")); err != nil {
+		t.Errorf("Expected -force to keep going, but got %v", err)
+	}
+	if n := len(server.Executed()); n != 3 {
+		t.Errorf("Expected both statements to be executed with -force, but %d were", n)
+	}
+}
+
+func TestMetadataCommands(t *testing.T) {
+	s, server, out, _, _ := newShell(t)
+	defer server.Close()
+	defer s.close()
+
+	// Record the schemas asked for.
+	var schemas []string
+	s.options.Middleware = []hivething.Middleware{func(call *hivething.Call, next func() error) error {
+		if req, ok := call.Request.(*tcliservice.TGetColumnsReq); ok && req.SchemaName != nil {
+			schemas = append(schemas, string(*req.SchemaName))
+		}
+		return next()
+	}}
+	if err := s.connect(server.Addr()); err != nil {
+		t.Fatal(err)
+	}
+
+	server.Handle("SELECT current_database()").
+		Column("_c0", tcliservice.TTypeId_STRING_TYPE).
+		Row("sales")
+	server.HandleMetadata("GetTables").
+		Column("TABLE_CAT", tcliservice.TTypeId_STRING_TYPE).
+		Column("TABLE_SCHEM", tcliservice.TTypeId_STRING_TYPE).
+		Column("TABLE_NAME", tcliservice.TTypeId_STRING_TYPE).
+		Column("TABLE_TYPE", tcliservice.TTypeId_STRING_TYPE).
+		Row("", "default", "foo", "TABLE")
+	server.HandleMetadata("GetColumns").
+		Column("TABLE_NAME", tcliservice.TTypeId_STRING_TYPE).
+		Column("COLUMN_NAME", tcliservice.TTypeId_STRING_TYPE).
+		Column("DATA_TYPE", tcliservice.TTypeId_INT_TYPE).
+		Column("TYPE_NAME", tcliservice.TTypeId_STRING_TYPE).
+		Row("foo", "id", int32(4), "int")
+
+	s.format = "csv"
+	if err := s.runScript(strings.NewReader("!tables\n!describe default.foo\n!describe foo\n")); err != nil {
+		t.Fatalf("runScript error: %v", err)
+	}
+
+	expected := "TABLE_SCHEM,TABLE_NAME,TABLE_TYPE\ndefault,foo,TABLE\nCOLUMN_NAME,TYPE_NAME\nid,int\nCOLUMN_NAME,TYPE_NAME\nid,int\n"
+	if out.String() != expected {
+		t.Errorf("Expected metadata\n%s\nbut got\n%s", expected, out.String())
+	}
+
+	// A table without a database is described in the current one.
+	if len(schemas) != 2 || schemas[0] != "default" || schemas[1] != "sales" {
+		t.Errorf("Expected columns of default.foo and sales.foo but got schemas %v", schemas)
+	}
+}
+
+func TestInterruptCancels(t *testing.T) {
+	s, server, _, _, interrupts := newShell(t)
+	defer server.Close()
+	defer s.close()
+
+	server.Handle("select * from huge").RunFor(1000000)
+
+	done := make(chan error)
+	go func() {
+		done <- s.runScript(strings.NewReader("select * from huge;"))
+	}()
+
+	// Interrupt once the query is running.
+	for server.OpenOperations() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	interrupts <- os.Interrupt
+
+	select {
+	case err := <-done:
+		if err != errCancelled {
+			t.Errorf("Expected the query to be cancelled but got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Interrupt didn't cancel the query")
+	}
+}
+
+func TestInterruptStopsPrinting(t *testing.T) {
+	s, server, out, _, interrupts := newShell(t)
+	defer server.Close()
+	defer s.close()
+
+	// An interrupt once the query has finished stops fetching its rows.
+	rows := hivething.NewStaticRowSet([]string{"id"}, [][]interface{}{{1}, {2}, {3}})
+	interruptible := &interruptibleRows{RowSet: rows, interrupts: interrupts}
+	if !interruptible.Next() {
+		t.Fatal("Expected a row before the interrupt")
+	}
+	interrupts <- os.Interrupt
+	if interruptible.Next() {
+		t.Error("Expected no rows after the interrupt")
+	}
+
+	s.format = "csv"
+	interrupts <- os.Interrupt
+	err := s.print(&interruptibleRows{RowSet: hivething.NewStaticRowSet([]string{"id"}, [][]interface{}{{1}}), interrupts: interrupts}, time.Now())
+	if err != errInterrupted {
+		t.Errorf("Expected printing to be interrupted but got %v", err)
+	}
+	if out.String() != "id\n" {
+		t.Errorf("Expected only the header before the interrupt but got %q", out.String())
+	}
+}
@@ -0,0 +1,179 @@
+package hivething
+
+import (
+	"math"
+	"reflect"
+
+	"github.com/derekgr/hivething/TCLIService"
+)
+
+// Describes a result column, as sql.ColumnType does for database/sql.
+type ColumnType struct {
+	name      string
+	typeName  string
+	scanType  reflect.Type
+	length    int64
+	hasLength bool
+	precision int64
+	scale     int64
+	hasScale  bool
+	comment   string
+	position  int
+}
+
+var (
+	scanTypeBool      = reflect.TypeOf(false)
+	scanTypeInt32     = reflect.TypeOf(int32(0))
+	scanTypeInt64     = reflect.TypeOf(int64(0))
+	scanTypeFloat64   = reflect.TypeOf(float64(0))
+	scanTypeString    = reflect.TypeOf("")
+	scanTypeInterface = reflect.TypeOf((*interface{})(nil)).Elem()
+)
+
+func newColumnType(desc *tcliservice.TColumnDesc) *ColumnType {
+	ct := &ColumnType{
+		name:     desc.ColumnName,
+		scanType: scanTypeString,
+		position: int(desc.Position),
+	}
+	if desc.Comment != nil {
+		ct.comment = *desc.Comment
+	}
+
+	if len(desc.TypeDesc.Types) == 0 {
+		ct.scanType = scanTypeInterface
+		return ct
+	}
+
+	entry := desc.TypeDesc.Types[0].PrimitiveEntry
+	ct.typeName = tcliservice.TYPE_NAMES[entry.TypeA1]
+
+	qualifier := func(name string) (int64, bool) {
+		if entry.TypeQualifiers == nil {
+			return 0, false
+		}
+		q := entry.TypeQualifiers.Qualifiers[name]
+		if q == nil || q.I32Value == nil {
+			return 0, false
+		}
+		return int64(*q.I32Value), true
+	}
+
+	switch entry.TypeA1 {
+	case tcliservice.TTypeId_BOOLEAN_TYPE:
+		ct.scanType = scanTypeBool
+	case tcliservice.TTypeId_TINYINT_TYPE, tcliservice.TTypeId_BIGINT_TYPE:
+		ct.scanType = scanTypeInt64
+	case tcliservice.TTypeId_SMALLINT_TYPE, tcliservice.TTypeId_INT_TYPE:
+		ct.scanType = scanTypeInt32
+	case tcliservice.TTypeId_FLOAT_TYPE, tcliservice.TTypeId_DOUBLE_TYPE:
+		ct.scanType = scanTypeFloat64
+	case tcliservice.TTypeId_NULL_TYPE:
+		ct.scanType = scanTypeInterface
+	case tcliservice.TTypeId_STRING_TYPE, tcliservice.TTypeId_BINARY_TYPE:
+		ct.length, ct.hasLength = math.MaxInt64, true
+	case tcliservice.TTypeId_VARCHAR_TYPE:
+		ct.length, ct.hasLength = qualifier(tcliservice.CHARACTER_MAXIMUM_LENGTH)
+	case tcliservice.TTypeId_DECIMAL_TYPE:
+		precision, hasPrecision := qualifier("precision")
+		scale, hasScale := qualifier("scale")
+		ct.precision, ct.scale, ct.hasScale = precision, scale, hasPrecision && hasScale
+	}
+
+	return ct
+}
+
+// Returns the name of the column.
+func (ct *ColumnType) Name() string {
+	return ct.name
+}
+
+// Returns the hive name of the column's type, such as "INT", "VARCHAR" or
+// "ARRAY", without any parameters or element types, or the empty string if
+// it's unknown.
+func (ct *ColumnType) DatabaseTypeName() string {
+	return ct.typeName
+}
+
+// Returns the Go type of the column's non-NULL values, suitable for
+// scanning into.
+func (ct *ColumnType) ScanType() reflect.Type {
+	return ct.scanType
+}
+
+// Returns whether the column may hold NULL. Hive doesn't report it, so ok
+// is always false, and any column may hold NULL.
+func (ct *ColumnType) Nullable() (nullable, ok bool) {
+	return true, false
+}
+
+// Returns the maximum length of a VARCHAR column, or math.MaxInt64 for
+// STRING and BINARY columns. ok is false for other types, and for VARCHAR
+// columns when hive doesn't report the length.
+func (ct *ColumnType) Length() (length int64, ok bool) {
+	return ct.length, ct.hasLength
+}
+
+// Returns the precision and scale of a DECIMAL column. ok is false for
+// other types, and for DECIMAL columns when hive doesn't report them, as it
+// doesn't before protocol version 6.
+func (ct *ColumnType) DecimalSize() (precision, scale int64, ok bool) {
+	return ct.precision, ct.scale, ct.hasScale
+}
+
+// Returns the column's comment, if it has one.
+func (ct *ColumnType) Comment() string {
+	return ct.comment
+}
+
+// Returns the column's 1-based position in the result set.
+func (ct *ColumnType) Position() int {
+	return ct.position
+}
+
+// Returns the types of the result columns, blocking if necessary until the
+// information is available.
+func (r *rowSet) ColumnTypes() ([]*ColumnType, error) {
+	if err := r.waitForSuccess(); err != nil {
+		return nil, err
+	}
+
+	types := make([]*ColumnType, len(r.columns))
+	for i, col := range r.columns {
+		types[i] = newColumnType(col)
+	}
+
+	return types, nil
+}
+
+// Static rows carry no type information, so column types are inferred
+// from the first non-NULL value in each column.
+func (r *staticRowSet) ColumnTypes() ([]*ColumnType, error) {
+	types := make([]*ColumnType, len(r.columns))
+	for i, name := range r.columns {
+		types[i] = &ColumnType{name: name, scanType: scanTypeInterface, position: i + 1}
+
+		for _, row := range r.rows {
+			if i >= len(row) || row[i] == nil {
+				continue
+			}
+
+			types[i].scanType = reflect.TypeOf(row[i])
+			switch row[i].(type) {
+			case bool:
+				types[i].typeName = "BOOLEAN"
+			case int32:
+				types[i].typeName = "INT"
+			case int64:
+				types[i].typeName = "BIGINT"
+			case float64:
+				types[i].typeName = "DOUBLE"
+			case string:
+				types[i].typeName = "STRING"
+			}
+			break
+		}
+	}
+
+	return types, nil
+}
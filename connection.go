@@ -3,29 +3,108 @@
 package hivething
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
+
 	"git.apache.org/thrift.git/lib/go/thrift"
 	"github.com/derekgr/hivething/TCLIService"
 )
 
 // Options for opened Hive sessions.
 type Options struct {
+	// Deprecated: use PollStrategy. If PollStrategy is nil and this is
+	// positive, Wait polls at this fixed interval.
 	PollIntervalSeconds int64
-	BatchSize           int64
+	// Controls how often Wait checks operation status. If nil, and
+	// PollIntervalSeconds is unset, DefaultPollStrategy is used.
+	PollStrategy PollStrategy
+	// The number of rows to fetch at a time, or with BatchMemoryBytes set,
+	// in the first fetch only.
+	BatchSize int64
+	// If positive, the number of rows fetched at a time is adjusted after
+	// each fetch to keep a batch's estimated size in memory under this
+	// many bytes, based on the size of the rows fetched so far.
+	BatchMemoryBytes int64
+	// If positive, Next fetches up to this many batches of BatchSize rows
+	// in the background while the caller works through the current one.
+	// Close the RowSet if iteration is abandoned early, to stop fetching.
+	PrefetchDepth int
+	// By default statements are executed asynchronously, so Query returns
+	// as soon as hive accepts the statement. If true, ExecuteStatement
+	// instead blocks until the statement has finished running.
+	Synchronous bool
+	// How to retry calls that fail transiently. By default nothing is
+	// retried.
+	Retry RetryPolicy
+	// If set, every call to the server is logged to it at debug level.
+	Logger Logger
+	// If set, receives measurements of calls to the server, fetched
+	// results and queries.
+	Metrics Metrics
+	// If set, traces queries and the calls made for them.
+	Tracer Tracer
+	// Wraps every call to the server, the first outermost. Logger and
+	// Metrics see calls as the Middleware passes them on to the server.
+	Middleware []Middleware
 }
 
 var (
-	DefaultOptions = Options{PollIntervalSeconds: 5, BatchSize: 10000}
+	DefaultOptions = Options{BatchSize: 10000}
 )
 
+func (o Options) pollStrategy() PollStrategy {
+	if o.PollStrategy != nil {
+		return o.PollStrategy
+	}
+
+	if o.PollIntervalSeconds > 0 {
+		return FixedInterval(time.Duration(o.PollIntervalSeconds) * time.Second)
+	}
+
+	return DefaultPollStrategy
+}
+
 type Connection struct {
-	thrift  *tcliservice.TCLIServiceClient
+	thrift  tcliservice.TCLIService
 	session *tcliservice.TSessionHandle
 	options Options
+	// The server's address, if connected with Connect, and the protocol
+	// version it agreed to; both are recorded in operation handles.
+	host     string
+	protocol tcliservice.TProtocolVersion
 }
 
+// Connect to the hiveserver2 at host and open a session, retrying as
+// options.Retry allows.
 func Connect(host string, options Options) (*Connection, error) {
+	var conn *Connection
+	_, err := options.Retry.do("Connect", true, func() error {
+		client, err := Dial(host)
+		if err != nil {
+			return err
+		}
+
+		conn, err = openSession(client, options)
+		if err != nil {
+			closeTransport(client)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	conn.host = host
+	return conn, nil
+}
+
+// Open a thrift client to the hiveserver2 at host, without starting a
+// session. Most callers want Connect; this is for wrapping the client,
+// e.g. to record its calls, before passing it to ConnectClient.
+func Dial(host string) (tcliservice.TCLIService, error) {
 	transport, err := thrift.NewTSocket(host)
 	if err != nil {
 		return nil, err
@@ -45,14 +124,126 @@ func Connect(host string, options Options) (*Connection, error) {
 		of this writing.
 	*/
 	protocol := thrift.NewTBinaryProtocolFactoryDefault()
-	client := tcliservice.NewTCLIServiceClientFactory(transport, protocol)
+	return tcliservice.NewTCLIServiceClientFactory(transport, protocol), nil
+}
+
+// Open a hive session using an existing thrift client, such as one from
+// Dial or a hivetest.Replayer, retrying as options.Retry allows.
+func ConnectClient(client tcliservice.TCLIService, options Options) (*Connection, error) {
+	var conn *Connection
+	_, err := options.Retry.do("OpenSession", false, func() (err error) {
+		conn, err = openSession(client, options)
+		return err
+	})
+
+	return conn, err
+}
 
+func openSession(client tcliservice.TCLIService, options Options) (*Connection, error) {
+	client = newInterceptedClient(client, options)
 	session, err := client.OpenSession(*tcliservice.NewTOpenSessionReq())
 	if err != nil {
 		return nil, err
 	}
 
-	return &Connection{client, session.SessionHandle, options}, nil
+	if !isSuccessStatus(session.Status) {
+		return nil, newServerError("OpenSession", session.Status)
+	}
+
+	return &Connection{
+		thrift:   newSyncClient(client),
+		session:  session.SessionHandle,
+		options:  options,
+		protocol: session.ServerProtocolVersion,
+	}, nil
+}
+
+// Returned when resuming a session the server no longer has, because it
+// timed out or was closed.
+var ErrSessionExpired = errors.New("Session has expired or been closed on the server")
+
+// Connect to the server recorded in an operation's handle, and resume the
+// session the operation was started in, instead of opening a new one, as
+// after a restart. The session must still be open on the server; if it
+// isn't, ErrSessionExpired is returned. Closing the Connection closes the
+// session.
+func ResumeSession(handle []byte, options Options) (*Connection, error) {
+	info, err := ParseHandle(handle)
+	if err != nil {
+		return nil, err
+	}
+	if info.Host == "" {
+		return nil, errors.New("Handle doesn't record its server; use ResumeSessionClient")
+	}
+
+	client, err := Dial(info.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := resumeSession(client, info, options)
+	if err != nil {
+		closeTransport(client)
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// Closes the socket under a client from Dial.
+func closeTransport(client tcliservice.TCLIService) {
+	if c, ok := client.(*tcliservice.TCLIServiceClient); ok {
+		c.Transport.Close()
+	}
+}
+
+// Like ResumeSession, using an existing thrift client to the server.
+func ResumeSessionClient(client tcliservice.TCLIService, handle []byte, options Options) (*Connection, error) {
+	info, err := ParseHandle(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	return resumeSession(client, info, options)
+}
+
+func resumeSession(client tcliservice.TCLIService, info *HandleInfo, options Options) (*Connection, error) {
+	if info.Session == nil {
+		return nil, errors.New("Handle doesn't record its session; it predates versioned handles")
+	}
+
+	conn := &Connection{
+		thrift:   newSyncClient(newInterceptedClient(client, options)),
+		session:  info.Session,
+		options:  options,
+		host:     info.Host,
+		protocol: info.ProtocolVersion,
+	}
+
+	// A cheap call that fails if the session is gone.
+	req := tcliservice.NewTGetInfoReq()
+	req.SessionHandle = *conn.session
+	req.InfoType = tcliservice.TGetInfoType_CLI_SERVER_NAME
+
+	resp, err := conn.thrift.GetInfo(*req)
+	if err != nil {
+		return nil, fmt.Errorf("Error in GetInfo: %+v, %v", resp, err)
+	}
+	if !isSuccessStatus(resp.Status) {
+		if isInvalidSession(resp.Status) {
+			return nil, ErrSessionExpired
+		}
+		return nil, newServerError("GetInfo", resp.Status)
+	}
+
+	return conn, nil
+}
+
+// Hiveserver2 reports an unknown session as an ERROR_STATUS with an
+// "Invalid SessionHandle" message, rather than INVALID_HANDLE_STATUS.
+func isInvalidSession(status tcliservice.TStatus) bool {
+	return status.StatusCode == tcliservice.TStatusCode_INVALID_HANDLE_STATUS ||
+		strings.Contains(status.GetErrorMessage(), "Invalid SessionHandle")
 }
 
 func (c *Connection) isOpen() bool {
@@ -67,7 +258,7 @@ func (c *Connection) Close() error {
 		closeReq.SessionHandle = *c.session
 		resp, err := c.thrift.CloseSession(*closeReq)
 		if err != nil {
-			return fmt.Errorf("Error closing session: ", resp, err)
+			return fmt.Errorf("Error closing session: %+v, %v", resp, err)
 		}
 
 		c.session = nil
@@ -77,25 +268,229 @@ func (c *Connection) Close() error {
 }
 
 // Issue a query on an open connection, returning a RowSet, which
-// can be later used to query the operation's status.
-func (c *Connection) Query(query string) (RowSet, error) {
+// can be later used to query the operation's status. Any args are bound
+// to ? and :name placeholders in the query as escaped HiveQL literals;
+// see Named.
+func (c *Connection) Query(query string, args ...interface{}) (RowSet, error) {
+	return c.query(context.Background(), query, nil, args)
+}
+
+// Like Query, but with Options.Tracer set, the query's span is a child of
+// any span in ctx. The thrift client can't abandon calls, so ctx doesn't
+// cancel the query; use RowSet.Cancel.
+func (c *Connection) QueryContext(ctx context.Context, query string, args ...interface{}) (RowSet, error) {
+	return c.query(ctx, query, nil, args)
+}
+
+// Like Query, but applies the given hive configuration (e.g.
+// "hive.exec.reducers.max" or "tez.queue.name") to this statement only,
+// without the session-wide side effects of issuing a SET.
+func (c *Connection) QueryWithConfig(query string, config map[string]string, args ...interface{}) (RowSet, error) {
+	return c.query(context.Background(), query, config, args)
+}
+
+func (c *Connection) query(ctx context.Context, query string, config map[string]string, args []interface{}) (RowSet, error) {
+	statement, err := bind(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := c.execute(ctx, query, statement, config)
+	if _, ok := err.(*ServerError); err != nil && !ok {
+		return nil, fmt.Errorf("Error in ExecuteStatement: %v", err)
+	}
+
+	return rows, err
+}
+
+// Issue a query and wait for it to complete, as Query then Wait do,
+// issuing it again if it fails in a way that Options.Retry allows
+// retrying, including the operation failing with a *ServerError. Only
+// use it for statements that are safe to run more than once, such as
+// reads. Returns the RowSet of the attempt that succeeded, and its
+// Status, whose Attempts counts the times the query was issued.
+func (c *Connection) QueryIdempotent(query string, args ...interface{}) (RowSet, *Status, error) {
+	return c.queryIdempotent(context.Background(), query, nil, args)
+}
+
+// Like QueryIdempotent, tracing each attempt from ctx as QueryContext
+// does. No attempts are made once ctx is done.
+func (c *Connection) QueryIdempotentContext(ctx context.Context, query string, args ...interface{}) (RowSet, *Status, error) {
+	return c.queryIdempotent(ctx, query, nil, args)
+}
+
+// Like QueryIdempotent, applying config to the statement as
+// QueryWithConfig does.
+func (c *Connection) QueryIdempotentWithConfig(query string, config map[string]string, args ...interface{}) (RowSet, *Status, error) {
+	return c.queryIdempotent(context.Background(), query, config, args)
+}
+
+func (c *Connection) queryIdempotent(ctx context.Context, query string, config map[string]string, args []interface{}) (RowSet, *Status, error) {
+	statement, err := bind(query, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		rows   RowSet
+		status *Status
+		// What the caller is told if the last attempt fails, which is
+		// better described than the error classified.
+		failure error
+	)
+	attempts, err := c.options.Retry.do("Query", false, func() error {
+		if rows != nil {
+			rows.Close()
+		}
+		rows, status, failure = nil, nil, nil
+
+		if err := ctx.Err(); err != nil {
+			failure = err
+			return err
+		}
+
+		var err error
+		if rows, err = c.execute(ctx, query, statement, config); err != nil {
+			failure = err
+			if _, ok := err.(*ServerError); !ok {
+				failure = fmt.Errorf("Error in ExecuteStatement: %v", err)
+			}
+			return err
+		}
+
+		status, failure = rows.Wait()
+		if failure != nil && status != nil && status.Error != nil {
+			return status.Error
+		}
+		return failure
+	})
+
+	if status != nil {
+		status.Attempts = attempts
+	}
+	if err != nil {
+		if rows != nil {
+			rows.Close()
+		}
+		return nil, status, failure
+	}
+
+	return rows, status, nil
+}
+
+// Execute a query, bound to statement, tracing it from ctx. Returns the
+// thrift client's error as it is, or a *ServerError if the server refused
+// it.
+func (c *Connection) execute(ctx context.Context, query, statement string, config map[string]string) (RowSet, error) {
+	trace := startQueryTrace(ctx, c.options, query)
+
 	executeReq := tcliservice.NewTExecuteStatementReq()
 	executeReq.SessionHandle = *c.session
-	executeReq.Statement = query
+	executeReq.Statement = statement
+	executeReq.ConfOverlay = config
+	executeReq.RunAsync = !c.options.Synchronous
 
+	span := trace.call("ExecuteStatement")
 	resp, err := c.thrift.ExecuteStatement(*executeReq)
+	if err == nil && !isSuccessStatus(resp.Status) {
+		err = newServerError("ExecuteStatement", resp.Status)
+	}
+	span.End(err)
 	if err != nil {
-		return nil, fmt.Errorf("Error in ExecuteStatement: %+v, %v", resp, err)
+		trace.end(err)
+		return nil, err
 	}
 
-	if !isSuccessStatus(resp.Status) {
-		return nil, fmt.Errorf("Error from server: %s", resp.Status.String())
+	if c.options.Metrics != nil {
+		c.options.Metrics.QueryStarted()
 	}
 
-	return newRowSet(c.thrift, resp.OperationHandle, c.options), nil
+	trace.setAttribute("hive.operation_id", operationID(resp.OperationHandle))
+	rows := newRowSet(c.thrift, resp.OperationHandle, c.options, c.source(HashQuery(statement)))
+	rows.trace = trace
+	rows.started = true
+	return rows, nil
+}
+
+// Returns what's recorded in the handles of operations started on this
+// connection, other than the operation itself.
+func (c *Connection) source(queryHash string) HandleInfo {
+	return HandleInfo{
+		Version:         HandleVersion,
+		Host:            c.host,
+		Session:         c.session,
+		ProtocolVersion: c.protocol,
+		QueryHash:       queryHash,
+		CreatedAt:       time.Now(),
+	}
+}
+
+// Construct a RowSet for an operation previously submitted on this
+// connection's session, using the prior operation's Handle(). Handles
+// recording a different server than the connection's are rejected; raw
+// handles from older versions of hivething are accepted as they are.
+func (c *Connection) Reattach(handle []byte) (RowSet, error) {
+	info, err := ParseHandle(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Host != "" && c.host != "" && info.Host != c.host {
+		return nil, fmt.Errorf("Handle is for an operation on %s, not %s", info.Host, c.host)
+	}
+
+	source := *info
+	if info.Version == 0 {
+		// Record what's known of it, so that its Handle is reissued in the
+		// current format. Its session and creation time aren't known.
+		source = HandleInfo{Version: HandleVersion, Host: c.host, ProtocolVersion: c.protocol}
+	}
+
+	return newRowSet(c.thrift, info.Operation, c.options, source), nil
 }
 
 func isSuccessStatus(p tcliservice.TStatus) bool {
 	status := p.GetStatusCode()
 	return status == tcliservice.TStatusCode_SUCCESS_STATUS || status == tcliservice.TStatusCode_SUCCESS_WITH_INFO_STATUS
 }
+
+// Delivered when an operation started with Submit finishes, with the
+// same values a call to Wait would have returned.
+type Completion struct {
+	Status *Status
+	Error  error
+}
+
+// Issue a query and return immediately, as Query does, without waiting
+// for the operation to complete. If done is non-nil, it is called from
+// a separate goroutine once the operation finishes; the RowSet should
+// not be used concurrently until then.
+func (c *Connection) Submit(query string, done func(Completion), args ...interface{}) (RowSet, error) {
+	rows, err := c.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if done != nil {
+		go func() {
+			status, err := rows.Wait()
+			done(Completion{status, err})
+		}()
+	}
+
+	return rows, nil
+}
+
+// Like Submit, but delivers the operation's Completion on the returned
+// channel, which receives exactly one value.
+func (c *Connection) SubmitChan(query string, args ...interface{}) (RowSet, <-chan Completion, error) {
+	ch := make(chan Completion, 1)
+	rows, err := c.Submit(query, func(done Completion) {
+		ch <- done
+	}, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return rows, ch, nil
+}
@@ -0,0 +1,1423 @@
+package hivething_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+	"github.com/derekgr/hivething"
+	"github.com/derekgr/hivething/TCLIService"
+	"github.com/derekgr/hivething/hivetest"
+)
+
+var testOptions = hivething.Options{BatchSize: 2, PollStrategy: hivething.FixedInterval(time.Millisecond)}
+
+// Starts a fake server with the "foo" table used by the integration tests,
+// and connects to it.
+func connectFake(t *testing.T) (*hivetest.Server, *hivething.Connection) {
+	server, err := hivetest.NewServer()
+	if err != nil {
+		t.Fatalf("Can't start fake server: %v", err)
+	}
+
+	server.Handle("SHOW TABLES").
+		Column("tab_name", tcliservice.TTypeId_STRING_TYPE).
+		Row("foo")
+
+	server.Handle("select * from foo").
+		Column("foo.id", tcliservice.TTypeId_INT_TYPE).
+		Column("foo.val", tcliservice.TTypeId_STRING_TYPE).
+		Row(int32(1), "foo").
+		Row(int32(2), "bar").
+		Row(int32(3), "baz").
+		RunFor(2)
+
+	db, err := hivething.Connect(server.Addr(), testOptions)
+	if err != nil {
+		server.Close()
+		t.Fatalf("Connect error: %v", err)
+	}
+
+	return server, db
+}
+
+func readFoo(t *testing.T, rows hivething.RowSet) {
+	var (
+		id    int
+		value string
+	)
+
+	col := rows.Columns()
+	if !reflect.DeepEqual(col, []string{"foo.id", "foo.val"}) {
+		t.Fatalf("Expected 'id' and 'value' columns, but got %v", col)
+	}
+
+	vals := make([]string, 0)
+	for rows.Next() {
+		if err := rows.Scan(&id, &value); err != nil {
+			t.Fatalf("Scan error: %v", err)
+		}
+
+		if id != len(vals)+1 {
+			t.Errorf("Expected row id to be %d but was %d", len(vals)+1, id)
+		}
+
+		vals = append(vals, value)
+	}
+
+	if !reflect.DeepEqual(vals, []string{"foo", "bar", "baz"}) {
+		t.Errorf("Expected 3 row values to be [foo, bar, baz] but was %v", vals)
+	}
+}
+
+func TestConnectionShowTables(t *testing.T) {
+	server, db := connectFake(t)
+	defer server.Close()
+	defer db.Close()
+
+	rows, err := db.Query("SHOW TABLES")
+	if err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+
+	var tableName string
+	for rows.Next() {
+		rows.Scan(&tableName)
+	}
+
+	if tableName != "foo" {
+		t.Errorf("Expected table 'foo' but found %s", tableName)
+	}
+}
+
+func TestConnectionQuery(t *testing.T) {
+	server, db := connectFake(t)
+	defer server.Close()
+	defer db.Close()
+
+	rows, err := db.Query("select * from foo")
+	if err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+
+	status, err := rows.Wait()
+	if err != nil {
+		t.Fatalf("Wait error: %v", err)
+	}
+
+	if !status.IsSuccess() {
+		t.Fatalf("Unsuccessful query execution: %v", status)
+	}
+
+	readFoo(t, rows)
+
+	executed := server.Executed()
+	if len(executed) != 1 || !executed[0].RunAsync {
+		t.Errorf("Expected one asynchronous ExecuteStatement, but got %+v", executed)
+	}
+}
+
+func TestConnectionQueryWithConfig(t *testing.T) {
+	server, db := connectFake(t)
+	defer server.Close()
+	defer db.Close()
+
+	config := map[string]string{"hive.exec.reducers.max": "1"}
+	if _, err := db.QueryWithConfig("select * from foo", config); err != nil {
+		t.Fatalf("Connection.QueryWithConfig error: %v", err)
+	}
+
+	if executed := server.Executed(); !reflect.DeepEqual(executed[0].ConfOverlay, config) {
+		t.Errorf("Expected conf overlay %v but got %v", config, executed[0].ConfOverlay)
+	}
+}
+
+func TestWaitWithProgress(t *testing.T) {
+	server, db := connectFake(t)
+	defer server.Close()
+	defer db.Close()
+
+	rows, err := db.Query("select * from foo")
+	if err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+
+	var reports []hivething.Progress
+	status, err := rows.WaitWithProgress(func(progress hivething.Progress) {
+		reports = append(reports, progress)
+	})
+	if err != nil || !status.IsSuccess() {
+		t.Fatalf("Unsuccessful query execution: %v, %v", status, err)
+	}
+
+	if len(reports) != 3 {
+		t.Fatalf("Expected progress after each of 3 polls but got %d reports", len(reports))
+	}
+	for i, progress := range reports[:2] {
+		if progress.Percent != -1 {
+			t.Errorf("Expected unknown progress while running but got %v in report %d", progress.Percent, i)
+		}
+	}
+
+	last := reports[2]
+	if last.Percent != 100 || last.Elapsed < reports[0].Elapsed {
+		t.Errorf("Unexpected final progress %+v", last)
+	}
+	var states []string
+	for _, s := range last.History {
+		states = append(states, s.String())
+	}
+	if expected := []string{"RUNNING_STATE", "FINISHED_STATE"}; !reflect.DeepEqual(states, expected) {
+		t.Errorf("Expected state history %v but got %v", expected, states)
+	}
+	if status.Progress == nil || status.Progress.Percent != 100 {
+		t.Errorf("Expected the final status to carry its progress but got %+v", status.Progress)
+	}
+
+	static := hivething.NewStaticRowSet([]string{"id"}, nil)
+	reports = nil
+	if _, err := static.WaitWithProgress(func(progress hivething.Progress) {
+		reports = append(reports, progress)
+	}); err != nil || len(reports) != 1 || reports[0].Percent != 100 {
+		t.Errorf("Expected a static RowSet to report itself complete but got %+v, %v", reports, err)
+	}
+}
+
+func TestServerProgress(t *testing.T) {
+	server, db := connectFake(t)
+	defer server.Close()
+	defer db.Close()
+
+	headers := []string{"VERTICES", "MODE", "STATUS", "TOTAL", "COMPLETED", "RUNNING", "PENDING", "FAILED", "KILLED"}
+	server.Handle("select count(*) from foo").
+		Column("_c0", tcliservice.TTypeId_BIGINT_TYPE).
+		Row(int64(1)).
+		RunFor(2).
+		Progress(&tcliservice.TProgressUpdateResp{
+			HeaderNames:          headers,
+			Rows:                 [][]string{{"Map 1", "container", "RUNNING", "4", "1", "3", "0", "0", "0"}, {"Reducer 2", "container", "INITED", "1", "0", "0", "1", "0", "0"}},
+			ProgressedPercentage: 0.2,
+			Status:               tcliservice.TJobExecutionStatus_IN_PROGRESS,
+		}, &tcliservice.TProgressUpdateResp{Status: tcliservice.TJobExecutionStatus_NOT_AVAILABLE})
+
+	rows, err := db.Query("select count(*) from foo")
+	if err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+	defer rows.Close()
+
+	var reports []hivething.Progress
+	if _, err := rows.WaitWithProgress(func(progress hivething.Progress) {
+		reports = append(reports, progress)
+	}); err != nil {
+		t.Fatalf("WaitWithProgress error: %v", err)
+	}
+
+	if len(reports) != 3 {
+		t.Fatalf("Expected progress after each of 3 polls but got %d reports", len(reports))
+	}
+	if reports[0].Percent != 20 {
+		t.Errorf("Expected the server's percentage but got %v", reports[0].Percent)
+	}
+	expected := []hivething.StageProgress{
+		{Name: "Map 1", Status: "RUNNING", Total: 4, Completed: 1, Running: 3},
+		{Name: "Reducer 2", Status: "INITED", Total: 1, Pending: 1},
+	}
+	if !reflect.DeepEqual(reports[0].Stages, expected) {
+		t.Errorf("Expected stages %+v but got %+v", expected, reports[0].Stages)
+	}
+	if reports[1].Percent != -1 || reports[1].Stages != nil {
+		t.Errorf("Expected unknown progress when the server has none but got %+v", reports[1])
+	}
+	if reports[2].Percent != 100 {
+		t.Errorf("Expected a finished query to be 100%% done but got %v", reports[2].Percent)
+	}
+}
+
+func TestConnectionQueryFailure(t *testing.T) {
+	server, db := connectFake(t)
+	defer server.Close()
+	defer db.Close()
+
+	server.Handle("select oops").FailExecute("ParseException")
+	if _, err := db.Query("select oops"); err == nil {
+		t.Error("Expected a statement rejected by the server to fail")
+	}
+
+	server.Handle("select * from bar").RunFor(1).Fail()
+	rows, err := db.Query("select * from bar")
+	if err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+
+	if _, err := rows.Wait(); err == nil {
+		t.Error("Expected an operation in ERROR_STATE to fail Wait")
+	}
+
+	if rows.Next() {
+		t.Error("Expected Next to be false for a failed operation")
+	}
+}
+
+func TestStatusServerError(t *testing.T) {
+	server, db := connectFake(t)
+	defer server.Close()
+	defer db.Close()
+
+	server.Handle("select * from missing").RunFor(1).FailWith("42S02", 10001, "Table not found 'missing'")
+	rows, err := db.Query("select * from missing")
+	if err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+
+	status, err := rows.Wait()
+	if err == nil || !strings.Contains(err.Error(), "Table not found 'missing' (state=42S02,code=10001)") {
+		t.Errorf("Expected Wait to report the server's error but got %v", err)
+	}
+	if status == nil || status.State != hivething.StateError {
+		t.Fatalf("Expected a status in ERROR_STATE but got %+v", status)
+	}
+
+	serverErr, ok := status.Error.(*hivething.ServerError)
+	if !ok || serverErr.SQLState != "42S02" || serverErr.Code != 10001 || serverErr.Message != "Table not found 'missing'" {
+		t.Errorf("Expected a structured server error but got %#v", status.Error)
+	}
+
+	if status.SubmittedAt.IsZero() || status.StartedAt.Before(status.SubmittedAt) || status.CompletedAt.Before(status.StartedAt) {
+		t.Errorf("Unexpected timing: submitted %v, started %v, completed %v", status.SubmittedAt, status.StartedAt, status.CompletedAt)
+	}
+}
+
+// Retries anything, promptly, recording the calls retried.
+func retryEverything(retried *[]string) hivething.RetryPolicy {
+	return hivething.RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     hivething.FixedInterval(time.Millisecond),
+		Retryable:   func(error) bool { return true },
+		OnRetry: func(call string, attempts int, err error) {
+			*retried = append(*retried, call)
+		},
+	}
+}
+
+func TestRetryConnectAndPoll(t *testing.T) {
+	server, db := connectFake(t)
+	defer server.Close()
+	defer db.Close()
+
+	var retried []string
+	options := testOptions
+	options.Retry = retryEverything(&retried)
+
+	server.FailNext("OpenSession", errors.New("Too many sessions"))
+	server.FailNext("OpenSession", errors.New("Too many sessions"))
+	retryingDB, err := hivething.Connect(server.Addr(), options)
+	if err != nil {
+		t.Fatalf("Expected Connect to succeed on its third attempt but got %v", err)
+	}
+	defer retryingDB.Close()
+
+	server.FailNext("GetOperationStatus", errors.New("Busy"))
+	server.FailNext("GetTables", errors.New("Busy"))
+
+	rows, err := retryingDB.Query("select * from foo")
+	if err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+	readFoo(t, rows)
+
+	server.HandleMetadata("GetTables").Column("TABLE_NAME", tcliservice.TTypeId_STRING_TYPE)
+	if _, err := retryingDB.GetTables("", "%"); err != nil {
+		t.Errorf("Expected GetTables to be retried but got %v", err)
+	}
+
+	expected := []string{"Connect", "Connect", "GetOperationStatus", "GetTables"}
+	if !reflect.DeepEqual(retried, expected) {
+		t.Errorf("Expected retries of %v but got %v", expected, retried)
+	}
+
+	// Without a policy, nothing is retried.
+	server.FailNext("GetOperationStatus", errors.New("Busy"))
+	if rows, err := db.Query("select * from foo"); err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	} else if _, err := rows.Poll(); err == nil {
+		t.Error("Expected Poll to fail without a retry policy")
+	}
+}
+
+func TestQueryIdempotent(t *testing.T) {
+	server, err := hivetest.NewServer()
+	if err != nil {
+		t.Fatalf("Can't start fake server: %v", err)
+	}
+	defer server.Close()
+
+	server.Handle("select * from flaky").RunFor(1).FailWith("08S01", 2, "Lost connection to the metastore")
+
+	options := testOptions
+	options.Retry = hivething.RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     hivething.FixedInterval(time.Millisecond),
+		Retryable: func(err error) bool {
+			serverErr, ok := err.(*hivething.ServerError)
+			return ok && serverErr.SQLState == "08S01"
+		},
+		// The second attempt succeeds.
+		OnRetry: func(call string, attempts int, err error) {
+			server.Handle("select * from flaky").
+				Column("flaky.id", tcliservice.TTypeId_INT_TYPE).
+				Row(int32(1))
+		},
+	}
+
+	db, err := hivething.Connect(server.Addr(), options)
+	if err != nil {
+		t.Fatalf("Connect error: %v", err)
+	}
+	defer db.Close()
+
+	rows, status, err := db.QueryIdempotent("select * from flaky")
+	if err != nil {
+		t.Fatalf("QueryIdempotent error: %v", err)
+	}
+	if !status.IsSuccess() || status.Attempts != 2 {
+		t.Errorf("Expected success on the second attempt but got %+v", status)
+	}
+	if n := len(server.Executed()); n != 2 {
+		t.Errorf("Expected the query to be issued twice but it was issued %d times", n)
+	}
+
+	var id int
+	if !rows.Next() || rows.Scan(&id) != nil || id != 1 {
+		t.Errorf("Expected to read the successful attempt's row")
+	}
+
+	server.Handle("select * from broken").RunFor(1).FailWith("42000", 40000, "Permission denied")
+	if _, status, err := db.QueryIdempotent("select * from broken"); err == nil || status.Attempts != 1 {
+		t.Errorf("Expected an error the policy doesn't retry to fail at once but got %+v, %v", status, err)
+	}
+
+	config := map[string]string{"tez.queue.name": "reports"}
+	if rows, _, err := db.QueryIdempotentWithConfig("select * from flaky", config); err != nil {
+		t.Errorf("QueryIdempotentWithConfig error: %v", err)
+	} else {
+		rows.Close()
+	}
+	executed := server.Executed()
+	if overlay := executed[len(executed)-1].ConfOverlay; !reflect.DeepEqual(overlay, config) {
+		t.Errorf("Expected the statement to be issued with %v but got %v", config, overlay)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := db.QueryIdempotentContext(ctx, "select * from flaky"); err != context.Canceled || len(server.Executed()) != len(executed) {
+		t.Errorf("Expected a done context to stop the query being issued but got %v", err)
+	}
+}
+
+func TestRetryConnectRedials(t *testing.T) {
+	// Nothing is listening once the server is closed.
+	server, err := hivetest.NewServer()
+	if err != nil {
+		t.Fatalf("Can't start fake server: %v", err)
+	}
+	server.Close()
+
+	var retried []string
+	options := testOptions
+	options.Retry = hivething.RetryPolicy{
+		MaxAttempts: 2,
+		Backoff:     hivething.FixedInterval(time.Millisecond),
+		OnRetry: func(call string, attempts int, err error) {
+			retried = append(retried, call)
+		},
+	}
+
+	if _, err := hivething.Connect(server.Addr(), options); err == nil {
+		t.Fatal("Expected Connect to a closed server to fail")
+	}
+	if expected := []string{"Connect"}; !reflect.DeepEqual(retried, expected) {
+		t.Errorf("Expected a failed dial to be retried but got retries of %v", retried)
+	}
+}
+
+func TestRowSetErr(t *testing.T) {
+	server, db := connectFake(t)
+	defer server.Close()
+	defer db.Close()
+
+	rows, err := db.Query("select * from foo")
+	if err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+
+	server.FailNext("FetchResults", errors.New("Disk full"))
+	if rows.Next() {
+		t.Fatal("Expected Next to fail")
+	}
+	if err := rows.Err(); err == nil || !strings.Contains(err.Error(), "Disk full") {
+		t.Errorf("Expected Err to report the failed fetch but got %v", err)
+	}
+
+	server.Handle("select * from bar").Fail()
+	if rows, err = db.Query("select * from bar"); err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+	if rows.Next() || rows.Err() == nil {
+		t.Errorf("Expected Err to report the failed query but got %v", rows.Err())
+	}
+}
+
+// Records what's reported to it.
+type recordedMetrics struct {
+	sync.Mutex
+	rpcs      map[string]int
+	failed    []string
+	rows      int
+	bytes     int64
+	started   int
+	completed []hivething.OperationState
+}
+
+func (m *recordedMetrics) RPC(method string, duration time.Duration, err error) {
+	m.Lock()
+	defer m.Unlock()
+	m.rpcs[method]++
+	if err != nil {
+		m.failed = append(m.failed, method)
+	}
+}
+
+func (m *recordedMetrics) Fetched(rows int, bytes int64) {
+	m.Lock()
+	defer m.Unlock()
+	m.rows += rows
+	m.bytes += bytes
+}
+
+func (m *recordedMetrics) QueryStarted() {
+	m.Lock()
+	defer m.Unlock()
+	m.started++
+}
+
+func (m *recordedMetrics) QueryCompleted(state hivething.OperationState, duration time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+	m.completed = append(m.completed, state)
+}
+
+func TestMetrics(t *testing.T) {
+	server, err := hivetest.NewServer()
+	if err != nil {
+		t.Fatalf("Can't start fake server: %v", err)
+	}
+	defer server.Close()
+
+	server.Handle("select * from foo").
+		Column("foo.val", tcliservice.TTypeId_STRING_TYPE).
+		Row("foo").Row("bar").Row("baz").
+		RunFor(2)
+	server.Handle("select * from bar").Fail()
+
+	metrics := &recordedMetrics{rpcs: map[string]int{}}
+	options := testOptions
+	options.Metrics = metrics
+
+	db, err := hivething.Connect(server.Addr(), options)
+	if err != nil {
+		t.Fatalf("Connect error: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("select * from foo")
+	if err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+	for rows.Next() {
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("Iteration error: %v", err)
+	}
+
+	if rows, err = db.Query("select * from bar"); err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+	rows.Wait()
+
+	metrics.Lock()
+	defer metrics.Unlock()
+
+	expected := map[string]int{"OpenSession": 1, "ExecuteStatement": 2, "GetOperationStatus": 4, "GetResultSetMetadata": 1, "FetchResults": 2}
+	if !reflect.DeepEqual(metrics.rpcs, expected) {
+		t.Errorf("Expected calls %v but got %v", expected, metrics.rpcs)
+	}
+	if len(metrics.failed) != 0 {
+		t.Errorf("Expected no calls to fail but got %v", metrics.failed)
+	}
+	if metrics.rows != 3 || metrics.bytes <= 0 {
+		t.Errorf("Expected 3 rows and their size to be fetched but got %d rows, %d bytes", metrics.rows, metrics.bytes)
+	}
+	if metrics.started != 2 || !reflect.DeepEqual(metrics.completed, []hivething.OperationState{hivething.StateFinished, hivething.StateError}) {
+		t.Errorf("Expected 2 queries to start and complete but got %d started, completed %v", metrics.started, metrics.completed)
+	}
+}
+
+func TestMetricsOnlyCompleteStartedQueries(t *testing.T) {
+	server, err := hivetest.NewServer()
+	if err != nil {
+		t.Fatalf("Can't start fake server: %v", err)
+	}
+	defer server.Close()
+
+	server.Handle("select 1").RunFor(1)
+	server.HandleMetadata("GetTables").Column("TABLE_NAME", tcliservice.TTypeId_STRING_TYPE)
+
+	metrics := &recordedMetrics{rpcs: map[string]int{}}
+	options := testOptions
+	options.Metrics = metrics
+
+	db, err := hivething.Connect(server.Addr(), options)
+	if err != nil {
+		t.Fatalf("Connect error: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("select 1")
+	if err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+	if _, err := rows.Wait(); err != nil {
+		t.Fatalf("Wait error: %v", err)
+	}
+
+	// Neither reattaching to the query nor a metadata call is a query
+	// started, so neither is reported complete.
+	handle, _ := rows.Handle()
+	reattached, err := db.Reattach(handle)
+	if err != nil {
+		t.Fatalf("Reattach error: %v", err)
+	}
+	if _, err := reattached.Wait(); err != nil {
+		t.Fatalf("Wait error: %v", err)
+	}
+
+	tables, err := db.GetTables("", "%")
+	if err != nil {
+		t.Fatalf("GetTables error: %v", err)
+	}
+	if _, err := tables.Wait(); err != nil {
+		t.Fatalf("Wait error: %v", err)
+	}
+
+	metrics.Lock()
+	defer metrics.Unlock()
+	if metrics.started != 1 || len(metrics.completed) != 1 {
+		t.Errorf("Expected 1 query to start and complete but got %d started, completed %v", metrics.started, metrics.completed)
+	}
+}
+
+// Records the spans started, with their parents'
+// names, which spanKey carries in a span's context.
+type recordedTracer struct {
+	sync.Mutex
+	spans []*recordedSpan
+}
+
+type spanKey struct{}
+
+type recordedSpan struct {
+	tracer     *recordedTracer
+	name       string
+	parent     string
+	attributes map[string]interface{}
+	ended      int
+	err        error
+}
+
+func (t *recordedTracer) Start(ctx context.Context, name string) (context.Context, hivething.Span) {
+	t.Lock()
+	defer t.Unlock()
+	parent, _ := ctx.Value(spanKey{}).(string)
+	span := &recordedSpan{tracer: t, name: name, parent: parent, attributes: map[string]interface{}{}}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanKey{}, name), span
+}
+
+func (s *recordedSpan) SetAttribute(key string, value interface{}) {
+	s.tracer.Lock()
+	defer s.tracer.Unlock()
+	s.attributes[key] = value
+}
+
+func (s *recordedSpan) End(err error) {
+	s.tracer.Lock()
+	defer s.tracer.Unlock()
+	s.ended++
+	s.err = err
+}
+
+func TestTracer(t *testing.T) {
+	server, err := hivetest.NewServer()
+	if err != nil {
+		t.Fatalf("Can't start fake server: %v", err)
+	}
+	defer server.Close()
+
+	server.Handle("select * from foo where val = 'secret'").
+		Column("foo.val", tcliservice.TTypeId_STRING_TYPE).
+		Row("foo").Row("bar").Row("baz").
+		RunFor(1)
+	server.Handle("select * from bar").Fail()
+
+	tracer := &recordedTracer{}
+	options := testOptions
+	options.Tracer = tracer
+
+	db, err := hivething.Connect(server.Addr(), options)
+	if err != nil {
+		t.Fatalf("Connect error: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.WithValue(context.Background(), spanKey{}, "caller")
+	rows, err := db.QueryContext(ctx, "select * from foo where val = ?", "secret")
+	if err != nil {
+		t.Fatalf("Connection.QueryContext error: %v", err)
+	}
+	for rows.Next() {
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("Iteration error: %v", err)
+	}
+	rows.Close()
+
+	tracer.Lock()
+	var names []string
+	for _, span := range tracer.spans {
+		names = append(names, span.name)
+		if span.ended != 1 || span.err != nil {
+			t.Errorf("Expected %s to end once without error, but it ended %d times with %v", span.name, span.ended, span.err)
+		}
+		if span.name != "hivething.Query" && span.parent != "hivething.Query" {
+			t.Errorf("Expected %s to be a child of the query's span, not %q", span.name, span.parent)
+		}
+	}
+	expected := []string{"hivething.Query", "hivething.ExecuteStatement", "hivething.GetOperationStatus", "hivething.GetOperationStatus",
+		"hivething.GetResultSetMetadata", "hivething.FetchResults", "hivething.FetchResults"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected spans %v but got %v", expected, names)
+	}
+
+	query := tracer.spans[0]
+	handle, _ := rows.Handle()
+	info, _ := hivething.ParseHandle(handle)
+	if query.parent != "caller" {
+		t.Errorf("Expected the query's span to be a child of the caller's, not %q", query.parent)
+	}
+	if query.attributes["db.statement"] != "select * from foo where val = ?" {
+		t.Errorf("Expected the query's text without its arguments but got %q", query.attributes["db.statement"])
+	}
+	if query.attributes["hive.operation_id"] != hex.EncodeToString(info.Operation.OperationId.Guid) {
+		t.Errorf("Expected the query's operation ID but got %v", query.attributes["hive.operation_id"])
+	}
+	if rows := tracer.spans[5].attributes["hive.rows"]; rows != 2 {
+		t.Errorf("Expected the first fetch to record 2 rows but got %v", rows)
+	}
+	tracer.spans = nil
+	tracer.Unlock()
+
+	if rows, err = db.QueryContext(ctx, "select * from bar"); err != nil {
+		t.Fatalf("Connection.QueryContext error: %v", err)
+	}
+	rows.Wait()
+
+	tracer.Lock()
+	if query := tracer.spans[0]; query.ended != 1 || query.err == nil || query.attributes["hive.operation_state"] != "ERROR_STATE" {
+		t.Errorf("Expected the failed query's span to end with its error, but got %+v", query)
+	}
+	tracer.spans = nil
+	tracer.Unlock()
+
+	server.Handle("select * from baz").RunFor(1000000)
+	if rows, err = db.QueryContext(ctx, "select * from baz"); err != nil {
+		t.Fatalf("Connection.QueryContext error: %v", err)
+	}
+	if err := rows.Cancel(); err != nil {
+		t.Fatalf("Cancel error: %v", err)
+	}
+
+	tracer.Lock()
+	defer tracer.Unlock()
+	if query := tracer.spans[0]; query.ended != 1 || query.err == nil || query.attributes["hive.operation_state"] != "CANCELED_STATE" {
+		t.Errorf("Expected the cancelled query's span to end with an error, but got %+v", query)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	// Only the fake server's queries are wanted.
+	server, db := connectFake(t)
+	defer server.Close()
+	db.Close()
+
+	var (
+		calls   []string
+		failing bool
+	)
+	audit := func(call *hivething.Call, next func() error) error {
+		calls = append(calls, call.Method)
+		return next()
+	}
+	rewrite := func(call *hivething.Call, next func() error) error {
+		if req, ok := call.Request.(*tcliservice.TExecuteStatementReq); ok {
+			req.Statement = strings.Replace(req.Statement, "foo_v1", "foo", 1)
+		}
+		return next()
+	}
+	inject := func(call *hivething.Call, next func() error) error {
+		if failing && call.Method == "FetchResults" {
+			return errors.New("Injected fault")
+		}
+		if err := next(); err != nil {
+			return err
+		}
+		if resp, ok := call.Response.(*tcliservice.TGetOperationStatusResp); ok {
+			calls = append(calls, resp.OperationState.String())
+		}
+		return nil
+	}
+
+	options := testOptions
+	options.Middleware = []hivething.Middleware{audit, rewrite, inject}
+	db, err := hivething.Connect(server.Addr(), options)
+	if err != nil {
+		t.Fatalf("Connect error: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("select * from foo_v1")
+	if err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+	readFoo(t, rows)
+
+	executed := server.Executed()
+	if statement := executed[len(executed)-1].Statement; statement != "select * from foo" {
+		t.Errorf("Expected the statement to be rewritten but got %q", statement)
+	}
+
+	expected := []string{"OpenSession", "ExecuteStatement",
+		"GetOperationStatus", "TOperationState_RUNNING_STATE",
+		"GetOperationStatus", "TOperationState_RUNNING_STATE",
+		"GetOperationStatus", "TOperationState_FINISHED_STATE",
+		"GetResultSetMetadata", "FetchResults", "FetchResults"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected calls %v but got %v", expected, calls)
+	}
+
+	if rows, err = db.Query("select * from foo"); err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+	failing = true
+	if rows.Next() || rows.Err() == nil || !strings.Contains(rows.Err().Error(), "Injected fault") {
+		t.Errorf("Expected the injected fault to fail Next but got %v", rows.Err())
+	}
+}
+
+func TestLogger(t *testing.T) {
+	server, err := hivetest.NewServer()
+	if err != nil {
+		t.Fatalf("Can't start fake server: %v", err)
+	}
+	defer server.Close()
+
+	server.Handle("select 1").Column("_c0", tcliservice.TTypeId_INT_TYPE).Row(int32(1))
+
+	var out bytes.Buffer
+	options := testOptions
+	options.Logger = slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	db, err := hivething.Connect(server.Addr(), options)
+	if err != nil {
+		t.Fatalf("Connect error: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("select 1")
+	if err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+	for rows.Next() {
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("Iteration error: %v", err)
+	}
+
+	handle, _ := rows.Handle()
+	info, _ := hivething.ParseHandle(handle)
+	operation := hex.EncodeToString(info.Operation.OperationId.Guid)
+
+	for _, method := range []string{"OpenSession", "ExecuteStatement", "GetOperationStatus", "FetchResults"} {
+		if !strings.Contains(out.String(), "method="+method+" duration=") {
+			t.Errorf("Expected %s to be logged with its duration, but got\n%s", method, out.String())
+		}
+	}
+	if n := strings.Count(out.String(), "operation="+operation); n < 3 {
+		t.Errorf("Expected calls on the operation to be logged with its ID, but got\n%s", out.String())
+	}
+}
+
+func TestCancel(t *testing.T) {
+	server, db := connectFake(t)
+	defer server.Close()
+	defer db.Close()
+
+	server.Handle("select * from huge").RunFor(1000000)
+	rows, err := db.Query("select * from huge")
+	if err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+
+	waited := make(chan error)
+	go func() {
+		_, err := rows.Wait()
+		waited <- err
+	}()
+
+	if err := rows.Cancel(); err != nil {
+		t.Fatalf("Cancel error: %v", err)
+	}
+
+	select {
+	case err := <-waited:
+		if err == nil || !strings.Contains(err.Error(), "CANCELED") {
+			t.Errorf("Expected Wait to report cancellation but got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait didn't return after Cancel")
+	}
+}
+
+func TestReattachToOperation(t *testing.T) {
+	server, db := connectFake(t)
+	defer server.Close()
+	defer db.Close()
+
+	oldRows, err := db.Query("select * from foo")
+	if err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+
+	handle, err := oldRows.Handle()
+	if err != nil {
+		t.Fatalf("Can't read handle: %v", err)
+	}
+
+	rows, err := hivething.Reattach(db, handle)
+	if err != nil {
+		t.Fatalf("Can't reattach: %v", err)
+	}
+
+	readFoo(t, rows)
+}
+
+func TestHandleInfo(t *testing.T) {
+	server, db := connectFake(t)
+	defer server.Close()
+	defer db.Close()
+
+	rows, err := db.Query("select * from foo")
+	if err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+
+	handle, err := rows.Handle()
+	if err != nil {
+		t.Fatalf("Can't read handle: %v", err)
+	}
+	if url.QueryEscape(string(handle)) != string(handle) {
+		t.Errorf("Expected a URL-safe handle but got %q", handle)
+	}
+
+	info, err := hivething.ParseHandle(handle)
+	if err != nil {
+		t.Fatalf("ParseHandle error: %v", err)
+	}
+	if info.Version != hivething.HandleVersion || info.Host != server.Addr() || info.Session == nil || info.Operation == nil {
+		t.Errorf("Unexpected handle info %+v", info)
+	}
+	if info.QueryHash != hivething.HashQuery("select * from foo") || info.CreatedAt.IsZero() {
+		t.Errorf("Expected the query's hash and creation time but got %+v", info)
+	}
+	if info.ProtocolVersion != tcliservice.TProtocolVersion_HIVE_CLI_SERVICE_PROTOCOL_V3 {
+		t.Errorf("Expected the session's protocol version but got %v", info.ProtocolVersion)
+	}
+
+	// Reattaching preserves what the handle records.
+	reattached, err := db.Reattach(handle)
+	if err != nil {
+		t.Fatalf("Can't reattach: %v", err)
+	}
+	if again, _ := reattached.Handle(); string(again) != string(handle) {
+		t.Errorf("Expected the reattached handle %q but got %q", handle, again)
+	}
+
+	if _, err := db.Reattach([]byte("hth99.e30")); err == nil || !strings.Contains(err.Error(), "Unsupported handle version 99") {
+		t.Errorf("Expected an unsupported version to be rejected but got %v", err)
+	}
+	if _, err := db.Reattach(append([]byte(nil), handle[:len(handle)-4]...)); err == nil {
+		t.Error("Expected a truncated handle to be rejected")
+	}
+}
+
+func TestReattachOtherServer(t *testing.T) {
+	server, db := connectFake(t)
+	defer server.Close()
+	defer db.Close()
+
+	other, otherDB := connectFake(t)
+	defer other.Close()
+	defer otherDB.Close()
+
+	rows, err := db.Query("select * from foo")
+	if err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+	handle, _ := rows.Handle()
+
+	_, err = otherDB.Reattach(handle)
+	if err == nil || !strings.Contains(err.Error(), server.Addr()) {
+		t.Errorf("Expected reattaching on another server to fail but got %v", err)
+	}
+}
+
+func TestReattachRawHandle(t *testing.T) {
+	server, db := connectFake(t)
+	defer server.Close()
+	defer db.Close()
+
+	oldRows, err := db.Query("select * from foo")
+	if err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+	handle, _ := oldRows.Handle()
+	info, _ := hivething.ParseHandle(handle)
+
+	// Handles used to be the thrift-serialized operation handle alone.
+	raw, err := thrift.NewTSerializer().Write(info.Operation)
+	if err != nil {
+		t.Fatalf("Can't serialize operation handle: %v", err)
+	}
+
+	rows, err := db.Reattach(raw)
+	if err != nil {
+		t.Fatalf("Can't reattach with a raw handle: %v", err)
+	}
+
+	reissued, _ := rows.Handle()
+	if info, err := hivething.ParseHandle(reissued); err != nil || info.Version != hivething.HandleVersion || info.Host != server.Addr() {
+		t.Errorf("Expected a raw handle to be reissued in the current format but got %+v, %v", info, err)
+	}
+
+	readFoo(t, rows)
+}
+
+func TestResumeSession(t *testing.T) {
+	server, db := connectFake(t)
+	defer server.Close()
+
+	oldRows, err := db.Query("select * from foo")
+	if err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+	handle, _ := oldRows.Handle()
+
+	// As after a restart, the original connection is abandoned unclosed.
+	resumed, err := hivething.ResumeSession(handle, testOptions)
+	if err != nil {
+		t.Fatalf("ResumeSession error: %v", err)
+	}
+	defer resumed.Close()
+
+	if n := server.OpenSessions(); n != 1 {
+		t.Errorf("Expected the session to be resumed rather than a new one opened, but %d are open", n)
+	}
+
+	rows, err := resumed.Reattach(handle)
+	if err != nil {
+		t.Fatalf("Can't reattach: %v", err)
+	}
+	readFoo(t, rows)
+
+	if _, err := resumed.Query("select * from foo"); err != nil {
+		t.Fatalf("Can't query in a resumed session: %v", err)
+	}
+	executed := server.Executed()
+	if len(executed) != 2 || !reflect.DeepEqual(executed[0].SessionHandle, executed[1].SessionHandle) {
+		t.Errorf("Expected both statements to run in the same session, but got %+v", executed)
+	}
+}
+
+func TestResumeExpiredSession(t *testing.T) {
+	server, db := connectFake(t)
+	defer server.Close()
+	defer db.Close()
+
+	rows, err := db.Query("select * from foo")
+	if err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+	handle, _ := rows.Handle()
+	info, _ := hivething.ParseHandle(handle)
+
+	// Other failures are reported as they are, while hiveserver2's own
+	// invalid session error is recognised whatever its status code.
+	var status *tcliservice.TStatus
+	options := testOptions
+	options.Middleware = []hivething.Middleware{func(call *hivething.Call, next func() error) error {
+		err := next()
+		if resp, ok := call.Response.(*tcliservice.TGetInfoResp); ok && status != nil {
+			resp.Status = *status
+		}
+		return err
+	}}
+
+	message := "Too many sessions"
+	status = &tcliservice.TStatus{StatusCode: tcliservice.TStatusCode_ERROR_STATUS, ErrorMessage: &message}
+	_, err = hivething.ResumeSession(handle, options)
+	if serr, ok := err.(*hivething.ServerError); !ok || serr.Call != "GetInfo" || serr.Message != message {
+		t.Errorf("Expected a GetInfo ServerError but got %v", err)
+	}
+
+	message = "Invalid SessionHandle: SessionHandle [1234]"
+	if _, err := hivething.ResumeSession(handle, options); err != hivething.ErrSessionExpired {
+		t.Errorf("Expected ErrSessionExpired for %q but got %v", message, err)
+	}
+
+	status = nil
+	server.ExpireSession(*info.Session)
+	if _, err := hivething.ResumeSession(handle, testOptions); err != hivething.ErrSessionExpired {
+		t.Errorf("Expected ErrSessionExpired but got %v", err)
+	}
+}
+
+func TestSubmitChan(t *testing.T) {
+	server, db := connectFake(t)
+	defer server.Close()
+	defer db.Close()
+
+	rows, done, err := db.SubmitChan("select * from foo")
+	if err != nil {
+		t.Fatalf("Connection.SubmitChan error: %v", err)
+	}
+
+	completion := <-done
+	if completion.Error != nil || !completion.Status.IsSuccess() {
+		t.Fatalf("Unsuccessful query execution: %+v", completion)
+	}
+
+	readFoo(t, rows)
+}
+
+func TestExecScript(t *testing.T) {
+	server, db := connectFake(t)
+	defer server.Close()
+	defer db.Close()
+
+	server.Handle("SET hivevar:x=1")
+	server.Handle("select * from bar").Fail()
+
+	script := "SET hivevar:x=1;\nselect * from ${tbl}; -- the table\nselect * from bar;\nSHOW TABLES;"
+
+	results, err := db.ExecScript(strings.NewReader(script), hivething.ScriptOptions{Vars: map[string]string{"tbl": "foo"}})
+	if err == nil {
+		t.Error("Expected ExecScript to stop at the failed statement")
+	}
+
+	if len(results) != 3 || results[2].Error == nil {
+		t.Fatalf("Expected 3 results, the last failed, but got %+v", results)
+	}
+
+	if results[0].Rows != nil || results[2].Rows != nil {
+		t.Errorf("Expected no Rows for the SET and the failed statement but got %+v", results)
+	}
+	readFoo(t, results[1].Rows)
+	results[1].Rows.Close()
+	if open := server.OpenOperations(); open != 0 {
+		t.Errorf("Expected every operation to be closed, but %d are open", open)
+	}
+
+	results, err = db.ExecScript(strings.NewReader(script), hivething.ScriptOptions{Vars: map[string]string{"tbl": "foo"}, ContinueOnError: true})
+	if err != nil {
+		t.Errorf("Expected ExecScript to continue past the failed statement, but got %v", err)
+	}
+
+	if len(results) != 4 {
+		t.Errorf("Expected 4 results but got %+v", results)
+	}
+}
+
+func TestConnectionGetTables(t *testing.T) {
+	server, db := connectFake(t)
+	defer server.Close()
+	defer db.Close()
+
+	server.HandleMetadata("GetTables").
+		Column("TABLE_SCHEM", tcliservice.TTypeId_STRING_TYPE).
+		Column("TABLE_NAME", tcliservice.TTypeId_STRING_TYPE).
+		Row("default", "foo")
+
+	rows, err := db.GetTables("default", "%")
+	if err != nil {
+		t.Fatalf("Connection.GetTables error: %v", err)
+	}
+
+	var schema, table string
+	for rows.Next() {
+		rows.Scan(&schema, &table)
+	}
+
+	if schema != "default" || table != "foo" {
+		t.Errorf("Expected table default.foo but got %s.%s", schema, table)
+	}
+}
+
+func qualifiedColumn(name string, typeId tcliservice.TTypeId, qualifiers map[string]int32, comment string) *tcliservice.TColumnDesc {
+	desc := tcliservice.NewTColumnDesc()
+	desc.ColumnName = name
+	desc.Comment = &comment
+
+	entry := tcliservice.TPrimitiveTypeEntry{TypeA1: typeId}
+	if len(qualifiers) > 0 {
+		entry.TypeQualifiers = &tcliservice.TTypeQualifiers{Qualifiers: map[string]*tcliservice.TTypeQualifierValue{}}
+		for name, value := range qualifiers {
+			value := value
+			entry.TypeQualifiers.Qualifiers[name] = &tcliservice.TTypeQualifierValue{I32Value: &value}
+		}
+	}
+	desc.TypeDesc.Types = []*tcliservice.TTypeEntry{{PrimitiveEntry: entry}}
+
+	return desc
+}
+
+func TestColumnTypes(t *testing.T) {
+	server, db := connectFake(t)
+	defer server.Close()
+	defer db.Close()
+
+	server.Handle("select * from products").
+		Column("products.id", tcliservice.TTypeId_BIGINT_TYPE).
+		ColumnDesc(qualifiedColumn("products.name", tcliservice.TTypeId_VARCHAR_TYPE,
+			map[string]int32{tcliservice.CHARACTER_MAXIMUM_LENGTH: 40}, "display name")).
+		ColumnDesc(qualifiedColumn("products.price", tcliservice.TTypeId_DECIMAL_TYPE,
+			map[string]int32{"precision": 10, "scale": 2}, "")).
+		Column("products.tags", tcliservice.TTypeId_ARRAY_TYPE)
+
+	rows, err := db.Query("select * from products")
+	if err != nil {
+		t.Fatalf("Query error: %v", err)
+	}
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatalf("ColumnTypes error: %v", err)
+	}
+
+	if len(types) != 4 {
+		t.Fatalf("Expected 4 column types but got %d", len(types))
+	}
+
+	id, name, price, tags := types[0], types[1], types[2], types[3]
+	if id.Name() != "products.id" || id.DatabaseTypeName() != "BIGINT" || id.ScanType() != reflect.TypeOf(int64(0)) {
+		t.Errorf("Unexpected id column type %s %s %v", id.Name(), id.DatabaseTypeName(), id.ScanType())
+	}
+	if _, ok := id.Length(); ok {
+		t.Error("Expected BIGINT column to have no length")
+	}
+
+	if length, ok := name.Length(); !ok || length != 40 || name.DatabaseTypeName() != "VARCHAR" {
+		t.Errorf("Expected VARCHAR(40) but got %s(%d)", name.DatabaseTypeName(), length)
+	}
+	if name.Comment() != "display name" || name.Position() != 2 {
+		t.Errorf("Unexpected comment %q or position %d", name.Comment(), name.Position())
+	}
+
+	if precision, scale, ok := price.DecimalSize(); !ok || precision != 10 || scale != 2 {
+		t.Errorf("Expected DECIMAL(10,2) but got (%d,%d)", precision, scale)
+	}
+
+	if tags.DatabaseTypeName() != "ARRAY" || tags.ScanType() != reflect.TypeOf("") {
+		t.Errorf("Expected ARRAY column scanned as string but got %s %v", tags.DatabaseTypeName(), tags.ScanType())
+	}
+
+	if nullable, ok := id.Nullable(); !nullable || ok {
+		t.Error("Expected nullability to be unknown")
+	}
+}
+
+func TestStaticColumnTypes(t *testing.T) {
+	rows := hivething.NewStaticRowSet([]string{"id", "name"}, [][]interface{}{{int32(1), nil}, {int32(2), "foo"}})
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatalf("ColumnTypes error: %v", err)
+	}
+
+	if types[0].DatabaseTypeName() != "INT" || types[1].DatabaseTypeName() != "STRING" {
+		t.Errorf("Expected types INT, STRING inferred but got %s, %s", types[0].DatabaseTypeName(), types[1].DatabaseTypeName())
+	}
+}
+
+func TestStream(t *testing.T) {
+	server, db := connectFake(t)
+	defer server.Close()
+	defer db.Close()
+
+	rows, err := db.Query("select * from foo")
+	if err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+
+	var (
+		id    int
+		value string
+		vals  []string
+	)
+
+	stream, errs := rows.Stream(context.Background(), 1)
+	for row := range stream {
+		if err := row.Scan(&id, &value); err != nil {
+			t.Fatalf("Row.Scan error: %v", err)
+		}
+		vals = append(vals, value)
+	}
+
+	if err := <-errs; err != nil {
+		t.Errorf("Stream error: %v", err)
+	}
+
+	if !reflect.DeepEqual(vals, []string{"foo", "bar", "baz"}) {
+		t.Errorf("Expected 3 row values to be [foo, bar, baz] but was %v", vals)
+	}
+
+	if open := server.OpenOperations(); open != 0 {
+		t.Errorf("Expected the streamed operation to be closed, but %d are open", open)
+	}
+}
+
+func TestStreamCancel(t *testing.T) {
+	server, db := connectFake(t)
+	defer server.Close()
+	defer db.Close()
+
+	rows, err := db.Query("select * from foo")
+	if err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, errs := rows.Stream(ctx, 0)
+
+	<-stream
+	cancel()
+
+	// With no buffer and nobody receiving, the stream can only give up.
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("Expected context.Canceled but got %v", err)
+	}
+
+	for range stream {
+	}
+
+	if open := server.OpenOperations(); open != 0 {
+		t.Errorf("Expected the canceled operation to be closed, but %d are open", open)
+	}
+}
+
+func TestNextPrefetch(t *testing.T) {
+	server, db := connectFake(t)
+	defer server.Close()
+	defer db.Close()
+
+	prefetching := testOptions
+	prefetching.PrefetchDepth = 2
+
+	db, err := hivething.Connect(server.Addr(), prefetching)
+	if err != nil {
+		t.Fatalf("Connect error: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("select * from foo")
+	if err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+
+	readFoo(t, rows)
+
+	if err := rows.Close(); err != nil {
+		t.Errorf("RowSet.Close error: %v", err)
+	}
+}
+
+// Reads 1000 rows in batches of 50 from a server with 2ms of latency per
+// call, spending 2ms processing each batch, as a consumer doing real work
+// would.
+func benchmarkFetch(b *testing.B, depth int) {
+	server, err := hivetest.NewServer()
+	if err != nil {
+		b.Fatalf("Can't start fake server: %v", err)
+	}
+	defer server.Close()
+
+	q := server.Handle("select * from big").Column("big.id", tcliservice.TTypeId_BIGINT_TYPE)
+	for i := 0; i < 1000; i++ {
+		q.Row(int64(i))
+	}
+	server.SetLatency(2 * time.Millisecond)
+
+	options := hivething.Options{BatchSize: 50, PrefetchDepth: depth, PollStrategy: hivething.FixedInterval(time.Millisecond)}
+	db, err := hivething.Connect(server.Addr(), options)
+	if err != nil {
+		b.Fatalf("Connect error: %v", err)
+	}
+	defer db.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rows, err := db.Query("select * from big")
+		if err != nil {
+			b.Fatalf("Connection.Query error: %v", err)
+		}
+
+		var id int64
+		for n := 1; rows.Next(); n++ {
+			rows.Scan(&id)
+			if n%50 == 0 {
+				time.Sleep(2 * time.Millisecond)
+			}
+		}
+		rows.Close()
+	}
+}
+
+func BenchmarkFetch(b *testing.B) {
+	benchmarkFetch(b, 0)
+}
+
+func BenchmarkFetchPrefetch1(b *testing.B) {
+	benchmarkFetch(b, 1)
+}
+
+func BenchmarkFetchPrefetch4(b *testing.B) {
+	benchmarkFetch(b, 4)
+}
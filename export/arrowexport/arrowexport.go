@@ -0,0 +1,282 @@
+// Package arrowexport converts the rows of a hivething.RowSet into Apache
+// Arrow record batches, and writes them as Arrow IPC streams or Parquet
+// files, a batch at a time.
+//
+// Column types are mapped from the result set schema hive reports:
+// BOOLEAN, TINYINT, SMALLINT, INT, BIGINT, FLOAT and DOUBLE to the arrow
+// types of the same width, STRING, VARCHAR and CHAR to utf8, BINARY to
+// binary, DECIMAL to decimal128, TIMESTAMP to a microsecond timestamp without
+// a time zone, and DATE to date32. Hiveserver2 doesn't describe the element
+// types of complex columns, so ARRAY, MAP, STRUCT and UNIONTYPE columns are
+// exported as utf8 holding hive's JSON text for them, unless their types
+// are declared in Options.ColumnTypes, in which case they're exported as
+// arrow lists, maps and structs.
+package arrowexport
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"github.com/derekgr/hivething"
+)
+
+// The number of rows in a record batch if Options.BatchRows is unset.
+const DefaultBatchRows = 10000
+
+// Options controlling conversion.
+type Options struct {
+	// The number of rows in each record batch, and so in each row group of a
+	// Parquet file. Defaults to DefaultBatchRows.
+	BatchRows int
+	// Hive type declarations for columns, keyed by column name, overriding
+	// the types hive reports for them, e.g. "array<string>" or
+	// "struct<id:bigint,price:decimal(10,2)>". Use them to export complex
+	// columns as nested arrow types, or to give the precision and scale of
+	// DECIMAL columns when hive doesn't report them; it reports neither
+	// before protocol version 6, so DECIMAL(38,18) is assumed. Result set
+	// columns named "table.column" may be keyed by the column alone.
+	ColumnTypes map[string]string
+	// Allocates the memory of record batches. Defaults to
+	// memory.DefaultAllocator.
+	Allocator memory.Allocator
+}
+
+// Returns the arrow schema the rows convert to, waiting for the operation
+// to succeed if necessary.
+func Schema(rows hivething.RowSet, options Options) (*arrow.Schema, error) {
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]arrow.Field, len(columnTypes))
+	for i, ct := range columnTypes {
+		typ := columnType(ct)
+		if decl, ok := declaredType(options.ColumnTypes, ct.Name()); ok {
+			if typ, err = parseType(decl); err != nil {
+				return nil, fmt.Errorf("Error mapping type of column %s: %v", ct.Name(), err)
+			}
+		}
+
+		fields[i] = arrow.Field{Name: ct.Name(), Type: typ, Nullable: true}
+		if comment := ct.Comment(); comment != "" {
+			fields[i].Metadata = arrow.NewMetadata([]string{"comment"}, []string{comment})
+		}
+	}
+
+	return arrow.NewSchema(fields, nil), nil
+}
+
+func declaredType(types map[string]string, column string) (string, bool) {
+	if decl, ok := types[column]; ok {
+		return decl, true
+	}
+
+	if i := strings.LastIndex(column, "."); i >= 0 {
+		decl, ok := types[column[i+1:]]
+		return decl, ok
+	}
+
+	return "", false
+}
+
+type recordReader struct {
+	refs      int64
+	rows      hivething.RowSet
+	schema    *arrow.Schema
+	mem       memory.Allocator
+	builder   *array.RecordBuilder
+	batchRows int
+	dest      []interface{}
+	vals      []interface{}
+	cur       arrow.RecordBatch
+	done      bool
+	err       error
+	count     int64
+}
+
+// Returns a reader of the rows as arrow record batches of up to
+// options.BatchRows rows, read from the RowSet as each is needed. Release
+// the reader when done with it.
+func NewRecordReader(rows hivething.RowSet, options Options) (array.RecordReader, error) {
+	return newRecordReader(rows, options)
+}
+
+func newRecordReader(rows hivething.RowSet, options Options) (*recordReader, error) {
+	schema, err := Schema(rows, options)
+	if err != nil {
+		return nil, err
+	}
+
+	batchRows := options.BatchRows
+	if batchRows <= 0 {
+		batchRows = DefaultBatchRows
+	}
+
+	mem := options.Allocator
+	if mem == nil {
+		mem = memory.DefaultAllocator
+	}
+
+	r := &recordReader{
+		refs:      1,
+		rows:      rows,
+		schema:    schema,
+		mem:       mem,
+		builder:   array.NewRecordBuilder(mem, schema),
+		batchRows: batchRows,
+		vals:      make([]interface{}, len(schema.Fields())),
+		dest:      make([]interface{}, len(schema.Fields())),
+	}
+	for i := range r.vals {
+		r.dest[i] = &r.vals[i]
+	}
+
+	return r, nil
+}
+
+func (r *recordReader) Retain() {
+	atomic.AddInt64(&r.refs, 1)
+}
+
+func (r *recordReader) Release() {
+	if atomic.AddInt64(&r.refs, -1) == 0 {
+		if r.cur != nil {
+			r.cur.Release()
+			r.cur = nil
+		}
+		r.builder.Release()
+	}
+}
+
+func (r *recordReader) Schema() *arrow.Schema {
+	return r.schema
+}
+
+// Reads the next batch of rows, returning false when there are none left
+// or an error occurs.
+func (r *recordReader) Next() bool {
+	if r.cur != nil {
+		r.cur.Release()
+		r.cur = nil
+	}
+
+	if r.done {
+		return false
+	}
+
+	n := 0
+	for n < r.batchRows {
+		if !r.rows.Next() {
+			if err := r.rows.Err(); err != nil {
+				r.fail(err)
+				return false
+			}
+			r.done = true
+			break
+		}
+
+		if err := r.rows.Scan(r.dest...); err != nil {
+			r.fail(err)
+			return false
+		}
+
+		for i, val := range r.vals {
+			if err := appendValue(r.builder.Field(i), val); err != nil {
+				r.fail(fmt.Errorf("Error converting column %s of row %d: %v", r.schema.Field(i).Name, r.count+int64(n)+1, err))
+				return false
+			}
+		}
+		n++
+	}
+
+	if n == 0 {
+		return false
+	}
+
+	r.cur = r.builder.NewRecordBatch()
+	r.count += int64(n)
+	return true
+}
+
+func (r *recordReader) fail(err error) {
+	r.err = err
+	r.done = true
+
+	// Discard the partial batch.
+	r.builder.NewRecordBatch().Release()
+}
+
+func (r *recordReader) RecordBatch() arrow.RecordBatch {
+	return r.cur
+}
+
+// Deprecated: Use RecordBatch instead.
+func (r *recordReader) Record() arrow.RecordBatch {
+	return r.cur
+}
+
+func (r *recordReader) Err() error {
+	return r.err
+}
+
+// Write the rows as an Arrow IPC stream, a record batch at a time.
+// Returns the number of rows written.
+func WriteIPC(w io.Writer, rows hivething.RowSet, options Options) (int64, error) {
+	r, err := newRecordReader(rows, options)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Release()
+
+	writer := ipc.NewWriter(w, ipc.WithSchema(r.schema), ipc.WithAllocator(r.mem))
+	return copyRecords(r, writer)
+}
+
+// Write the rows as a Parquet file, with a row group per record batch.
+// Returns the number of rows written.
+func WriteParquet(w io.Writer, rows hivething.RowSet, options Options) (int64, error) {
+	r, err := newRecordReader(rows, options)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Release()
+
+	// Hide any Close method of w, which the parquet writer would call.
+	writer, err := pqarrow.NewFileWriter(r.schema, struct{ io.Writer }{w}, nil, pqarrow.DefaultWriterProps())
+	if err != nil {
+		return 0, err
+	}
+
+	return copyRecords(r, writer)
+}
+
+type recordWriter interface {
+	Write(arrow.RecordBatch) error
+	Close() error
+}
+
+func copyRecords(r *recordReader, w recordWriter) (int64, error) {
+	var written int64
+	for r.Next() {
+		if err := w.Write(r.RecordBatch()); err != nil {
+			w.Close()
+			return written, err
+		}
+		written = r.count
+	}
+
+	err := r.Err()
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+
+	return written, err
+}
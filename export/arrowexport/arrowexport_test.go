@@ -0,0 +1,295 @@
+package arrowexport
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/decimal128"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"github.com/derekgr/hivething"
+	"github.com/derekgr/hivething/TCLIService"
+	"github.com/derekgr/hivething/hivetest"
+)
+
+func decimalColumn(name string, precision, scale int32) *tcliservice.TColumnDesc {
+	desc := tcliservice.NewTColumnDesc()
+	desc.ColumnName = name
+	desc.TypeDesc.Types = []*tcliservice.TTypeEntry{{
+		PrimitiveEntry: tcliservice.TPrimitiveTypeEntry{
+			TypeA1: tcliservice.TTypeId_DECIMAL_TYPE,
+			TypeQualifiers: &tcliservice.TTypeQualifiers{Qualifiers: map[string]*tcliservice.TTypeQualifierValue{
+				"precision": {I32Value: &precision},
+				"scale":     {I32Value: &scale},
+			}},
+		},
+	}}
+	return desc
+}
+
+func queryOrders(t *testing.T) (hivething.RowSet, func()) {
+	server, err := hivetest.NewServer()
+	if err != nil {
+		t.Fatalf("Can't start fake server: %v", err)
+	}
+
+	server.Handle("select * from orders").
+		Column("orders.id", tcliservice.TTypeId_BIGINT_TYPE).
+		Column("orders.qty", tcliservice.TTypeId_INT_TYPE).
+		ColumnDesc(decimalColumn("orders.price", 10, 2)).
+		Column("orders.placed", tcliservice.TTypeId_TIMESTAMP_TYPE).
+		Column("orders.day", tcliservice.TTypeId_DATE_TYPE).
+		Column("orders.tags", tcliservice.TTypeId_ARRAY_TYPE).
+		Column("orders.attrs", tcliservice.TTypeId_MAP_TYPE).
+		Column("orders.ship", tcliservice.TTypeId_STRUCT_TYPE).
+		Row(int64(1), int32(3), "9.99", "2014-03-01 12:30:00.5", "2014-03-01",
+			`["a","b"]`, `{1:"x",2:"y"}`, `{"city":"Oslo","zip":150}`).
+		Row(int64(2), nil, nil, nil, nil, nil, nil, nil).
+		Row(int64(3), int32(1), "100.00", "9999-12-31 23:59:59.999999", "2014-03-02",
+			`[]`, `{}`, `{"city":null,"zip":7}`)
+
+	db, err := hivething.Connect(server.Addr(), hivething.Options{BatchSize: 2, PollStrategy: hivething.FixedInterval(time.Millisecond)})
+	if err != nil {
+		server.Close()
+		t.Fatalf("Connect error: %v", err)
+	}
+
+	rows, err := db.Query("select * from orders")
+	if err != nil {
+		db.Close()
+		server.Close()
+		t.Fatalf("Query error: %v", err)
+	}
+
+	return rows, func() {
+		db.Close()
+		server.Close()
+	}
+}
+
+var orderTypes = map[string]string{
+	"tags":  "array<string>",
+	"attrs": "map<int,string>",
+	"ship":  "struct<city:string,zip:int>",
+}
+
+func TestSchema(t *testing.T) {
+	rows, done := queryOrders(t)
+	defer done()
+
+	schema, err := Schema(rows, Options{ColumnTypes: orderTypes})
+	if err != nil {
+		t.Fatalf("Schema error: %v", err)
+	}
+
+	expected := []arrow.DataType{
+		arrow.PrimitiveTypes.Int64,
+		arrow.PrimitiveTypes.Int32,
+		&arrow.Decimal128Type{Precision: 10, Scale: 2},
+		&arrow.TimestampType{Unit: arrow.Microsecond},
+		arrow.FixedWidthTypes.Date32,
+		arrow.ListOf(arrow.BinaryTypes.String),
+		arrow.MapOf(arrow.PrimitiveTypes.Int32, arrow.BinaryTypes.String),
+		arrow.StructOf(
+			arrow.Field{Name: "city", Type: arrow.BinaryTypes.String, Nullable: true},
+			arrow.Field{Name: "zip", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+		),
+	}
+	for i, typ := range expected {
+		if got := schema.Field(i).Type; !arrow.TypeEqual(got, typ) {
+			t.Errorf("Expected column %s to have type %v but got %v", schema.Field(i).Name, typ, got)
+		}
+	}
+
+	// Without declared types, complex columns are hive's JSON text.
+	rows, done = queryOrders(t)
+	defer done()
+
+	schema, err = Schema(rows, Options{})
+	if err != nil {
+		t.Fatalf("Schema error: %v", err)
+	}
+	if got := schema.Field(5).Type; !arrow.TypeEqual(got, arrow.BinaryTypes.String) {
+		t.Errorf("Expected an undeclared array column to be utf8 but got %v", got)
+	}
+}
+
+func TestRecordReader(t *testing.T) {
+	rows, done := queryOrders(t)
+	defer done()
+
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	r, err := NewRecordReader(rows, Options{BatchRows: 2, ColumnTypes: orderTypes, Allocator: mem})
+	if err != nil {
+		t.Fatalf("NewRecordReader error: %v", err)
+	}
+	defer r.Release()
+
+	var sizes []int64
+	var last arrow.RecordBatch
+	for r.Next() {
+		sizes = append(sizes, r.RecordBatch().NumRows())
+		if last != nil {
+			last.Release()
+		}
+		last = r.RecordBatch()
+		last.Retain()
+	}
+	if err := r.Err(); err != nil {
+		t.Fatalf("RecordReader error: %v", err)
+	}
+	defer last.Release()
+
+	if len(sizes) != 2 || sizes[0] != 2 || sizes[1] != 1 {
+		t.Errorf("Expected batches of 2 and 1 rows but got %v", sizes)
+	}
+
+	price := last.Column(2).(*array.Decimal128)
+	if got := price.Value(0); got != decimal128.FromI64(10000) {
+		t.Errorf("Expected price 100.00 but got %s", price.ValueStr(0))
+	}
+
+	placed := last.Column(3).(*array.Timestamp)
+	if got := placed.Value(0).ToTime(arrow.Microsecond); !got.Equal(time.Date(9999, 12, 31, 23, 59, 59, 999999000, time.UTC)) {
+		t.Errorf("Expected timestamp 9999-12-31 23:59:59.999999 but got %v", got)
+	}
+
+	ship := last.Column(7).(*array.Struct)
+	if !ship.Field(0).IsNull(0) || ship.Field(1).(*array.Int32).Value(0) != 7 {
+		t.Errorf("Expected struct {null 7} but got %v", ship.ValueStr(0))
+	}
+}
+
+func TestWriteParquet(t *testing.T) {
+	rows, done := queryOrders(t)
+	defer done()
+
+	var out bytes.Buffer
+	n, err := WriteParquet(&out, rows, Options{BatchRows: 2, ColumnTypes: orderTypes})
+	if err != nil {
+		t.Fatalf("WriteParquet error: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 rows written but got %d", n)
+	}
+
+	reader, err := file.NewParquetReader(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("Can't read parquet output: %v", err)
+	}
+	if groups := reader.NumRowGroups(); groups != 2 {
+		t.Errorf("Expected a row group per batch, 2, but got %d", groups)
+	}
+
+	table, err := pqarrow.ReadTable(context.Background(), bytes.NewReader(out.Bytes()), nil, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	if err != nil {
+		t.Fatalf("Can't read parquet output: %v", err)
+	}
+	defer table.Release()
+
+	if table.NumRows() != 3 {
+		t.Errorf("Expected 3 rows read back but got %d", table.NumRows())
+	}
+
+	attrs := table.Column(6).Data().Chunk(0).(*array.Map)
+	if keys := attrs.Keys().(*array.Int32); keys.Len() != 2 || keys.Value(0) != 1 || keys.Value(1) != 2 {
+		t.Errorf("Expected map keys [1 2] but got %v", keys)
+	}
+}
+
+func TestWriteIPC(t *testing.T) {
+	rows, done := queryOrders(t)
+	defer done()
+
+	var out bytes.Buffer
+	if _, err := WriteIPC(&out, rows, Options{}); err != nil {
+		t.Fatalf("WriteIPC error: %v", err)
+	}
+
+	r, err := ipc.NewReader(&out)
+	if err != nil {
+		t.Fatalf("Can't read IPC output: %v", err)
+	}
+	defer r.Release()
+
+	var total int64
+	for r.Next() {
+		rec := r.RecordBatch()
+		total += rec.NumRows()
+
+		tags := rec.Column(5).(*array.String)
+		if got := tags.Value(0); got != `["a","b"]` {
+			t.Errorf("Expected undeclared array column as JSON text but got %q", got)
+		}
+	}
+	if total != 3 {
+		t.Errorf("Expected 3 rows read back but got %d", total)
+	}
+}
+
+func TestParseType(t *testing.T) {
+	tests := map[string]arrow.DataType{
+		"INT":                  arrow.PrimitiveTypes.Int32,
+		"varchar(20)":          arrow.BinaryTypes.String,
+		"decimal":              &arrow.Decimal128Type{Precision: 10, Scale: 0},
+		"decimal(12, 4)":       &arrow.Decimal128Type{Precision: 12, Scale: 4},
+		"array<array<bigint>>": arrow.ListOf(arrow.ListOf(arrow.PrimitiveTypes.Int64)),
+		"map<string,array<double>>": arrow.MapOf(arrow.BinaryTypes.String,
+			arrow.ListOf(arrow.PrimitiveTypes.Float64)),
+		"struct<Name:string, at:timestamp>": arrow.StructOf(
+			arrow.Field{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+			arrow.Field{Name: "at", Type: &arrow.TimestampType{Unit: arrow.Microsecond}, Nullable: true},
+		),
+	}
+
+	for decl, expected := range tests {
+		typ, err := parseType(decl)
+		if err != nil {
+			t.Errorf("Error parsing %q: %v", decl, err)
+			continue
+		}
+		if !arrow.TypeEqual(typ, expected) {
+			t.Errorf("Expected %q to parse as %v but got %v", decl, expected, typ)
+		}
+	}
+
+	for _, decl := range []string{"", "array<int", "map<int>", "uniontype<int,string>", "int extra"} {
+		if _, err := parseType(decl); err == nil {
+			t.Errorf("Expected an error parsing %q", decl)
+		}
+	}
+}
+
+func TestParseComplex(t *testing.T) {
+	val, err := parseComplex(`{1:[true,null],"k":{"n":-1.5e3,"s":"a\"b"}}`)
+	if err != nil {
+		t.Fatalf("parseComplex error: %v", err)
+	}
+
+	fields := val.([]field)
+	if fields[0].key != number("1") {
+		t.Errorf("Expected unquoted key 1 but got %#v", fields[0].key)
+	}
+	if list := fields[0].value.([]interface{}); list[0] != true || list[1] != nil {
+		t.Errorf("Expected [true null] but got %v", list)
+	}
+
+	inner := fields[1].value.([]field)
+	if inner[0].value != number("-1.5e3") || inner[1].value != `a"b` {
+		t.Errorf("Unexpected nested object %v", inner)
+	}
+
+	for _, bad := range []string{"", "[1,", `{"a" 1}`, `"open`, "[1]]"} {
+		if _, err := parseComplex(bad); err == nil {
+			t.Errorf("Expected an error parsing %q", bad)
+		}
+	}
+}
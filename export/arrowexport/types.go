@@ -0,0 +1,236 @@
+package arrowexport
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/derekgr/hivething"
+)
+
+// Hive's maximum decimal precision, and the scale assumed for DECIMAL
+// columns when hive doesn't report one.
+const (
+	maxDecimalPrecision = 38
+	defaultDecimalScale = 18
+)
+
+// Returns the arrow type of a column, as hive describes it. Hiveserver2
+// describes complex columns only as ARRAY, MAP, STRUCT or UNIONTYPE, without
+// their element types, so they map to strings holding hive's JSON text.
+func columnType(ct *hivething.ColumnType) arrow.DataType {
+	switch ct.DatabaseTypeName() {
+	case "BOOLEAN":
+		return arrow.FixedWidthTypes.Boolean
+	case "TINYINT":
+		return arrow.PrimitiveTypes.Int8
+	case "SMALLINT":
+		return arrow.PrimitiveTypes.Int16
+	case "INT":
+		return arrow.PrimitiveTypes.Int32
+	case "BIGINT":
+		return arrow.PrimitiveTypes.Int64
+	case "FLOAT":
+		return arrow.PrimitiveTypes.Float32
+	case "DOUBLE":
+		return arrow.PrimitiveTypes.Float64
+	case "TIMESTAMP":
+		return timestampType
+	case "DATE":
+		return arrow.FixedWidthTypes.Date32
+	case "BINARY":
+		return arrow.BinaryTypes.Binary
+	case "NULL":
+		return arrow.Null
+	case "DECIMAL":
+		typ := &arrow.Decimal128Type{Precision: maxDecimalPrecision, Scale: defaultDecimalScale}
+		if precision, scale, ok := ct.DecimalSize(); ok {
+			typ.Precision, typ.Scale = int32(precision), int32(scale)
+		}
+		return typ
+	default:
+		return arrow.BinaryTypes.String
+	}
+}
+
+// Hive timestamps have no time zone. They have nanosecond precision, but
+// nanoseconds since the epoch only reach the years 1678 to 2262, which
+// leaves out sentinels such as 9999-12-31, so they're kept to microseconds.
+var timestampType = &arrow.TimestampType{Unit: arrow.Microsecond}
+
+// Returns the arrow type for a hive type declaration, as written in DDL or
+// returned by DESCRIBE and GetColumns, such as "array<struct<a:int,b:string>>".
+func parseType(decl string) (arrow.DataType, error) {
+	p := &typeParser{s: decl}
+	typ, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("Can't parse hive type %q: %v", decl, err)
+	}
+
+	p.skipSpace()
+	if p.i < len(p.s) {
+		return nil, fmt.Errorf("Can't parse hive type %q: unexpected %q", decl, p.s[p.i:])
+	}
+
+	return typ, nil
+}
+
+type typeParser struct {
+	s string
+	i int
+}
+
+func (p *typeParser) skipSpace() {
+	for p.i < len(p.s) && strings.ContainsRune(" \t\r\n", rune(p.s[p.i])) {
+		p.i++
+	}
+}
+
+// Returns the next identifier, lowercased, or the empty string.
+func (p *typeParser) ident() string {
+	p.skipSpace()
+	start := p.i
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			break
+		}
+		p.i++
+	}
+	return strings.ToLower(p.s[start:p.i])
+}
+
+func (p *typeParser) expect(c byte) error {
+	p.skipSpace()
+	if p.i >= len(p.s) || p.s[p.i] != c {
+		return fmt.Errorf("expected %q at offset %d", c, p.i)
+	}
+	p.i++
+	return nil
+}
+
+func (p *typeParser) peek(c byte) bool {
+	p.skipSpace()
+	return p.i < len(p.s) && p.s[p.i] == c
+}
+
+// Parses parenthesized integer parameters, as of decimal(10,2), if present.
+func (p *typeParser) params() ([]int32, error) {
+	if !p.peek('(') {
+		return nil, nil
+	}
+	p.i++
+
+	var params []int32
+	for {
+		n, err := strconv.ParseInt(p.ident(), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("bad type parameter at offset %d", p.i)
+		}
+		params = append(params, int32(n))
+
+		if p.peek(',') {
+			p.i++
+			continue
+		}
+		return params, p.expect(')')
+	}
+}
+
+func (p *typeParser) parse() (arrow.DataType, error) {
+	name := p.ident()
+	switch name {
+	case "boolean":
+		return arrow.FixedWidthTypes.Boolean, nil
+	case "tinyint":
+		return arrow.PrimitiveTypes.Int8, nil
+	case "smallint":
+		return arrow.PrimitiveTypes.Int16, nil
+	case "int", "integer":
+		return arrow.PrimitiveTypes.Int32, nil
+	case "bigint":
+		return arrow.PrimitiveTypes.Int64, nil
+	case "float":
+		return arrow.PrimitiveTypes.Float32, nil
+	case "double":
+		return arrow.PrimitiveTypes.Float64, nil
+	case "timestamp":
+		return timestampType, nil
+	case "date":
+		return arrow.FixedWidthTypes.Date32, nil
+	case "binary":
+		return arrow.BinaryTypes.Binary, nil
+	case "string", "varchar", "char":
+		_, err := p.params()
+		return arrow.BinaryTypes.String, err
+	case "decimal", "numeric":
+		params, err := p.params()
+		if err != nil {
+			return nil, err
+		}
+		// Hive's DECIMAL without parameters is DECIMAL(10,0).
+		typ := &arrow.Decimal128Type{Precision: 10, Scale: 0}
+		if len(params) > 0 {
+			typ.Precision = params[0]
+		}
+		if len(params) > 1 {
+			typ.Scale = params[1]
+		}
+		return typ, nil
+	case "array":
+		if err := p.expect('<'); err != nil {
+			return nil, err
+		}
+		elem, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		return arrow.ListOf(elem), p.expect('>')
+	case "map":
+		if err := p.expect('<'); err != nil {
+			return nil, err
+		}
+		key, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(','); err != nil {
+			return nil, err
+		}
+		value, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		return arrow.MapOf(key, value), p.expect('>')
+	case "struct":
+		if err := p.expect('<'); err != nil {
+			return nil, err
+		}
+		var fields []arrow.Field
+		for {
+			field := p.ident()
+			if field == "" {
+				return nil, fmt.Errorf("expected a field name at offset %d", p.i)
+			}
+			if err := p.expect(':'); err != nil {
+				return nil, err
+			}
+			typ, err := p.parse()
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, arrow.Field{Name: field, Type: typ, Nullable: true})
+
+			if p.peek(',') {
+				p.i++
+				continue
+			}
+			return arrow.StructOf(fields...), p.expect('>')
+		}
+	case "":
+		return nil, fmt.Errorf("expected a type at offset %d", p.i)
+	default:
+		return nil, fmt.Errorf("unsupported type %s", name)
+	}
+}
@@ -0,0 +1,356 @@
+package arrowexport
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/decimal128"
+)
+
+// A number in the text of a complex value, kept as written so decimals
+// don't lose precision.
+type number string
+
+// A field of an object in the text of a complex value. Objects are kept as
+// fields in order, since their keys needn't be strings.
+type field struct {
+	key   interface{}
+	value interface{}
+}
+
+// Parses the text hive sends for a complex value. It's JSON, except that
+// map keys are written unquoted when they aren't strings, e.g. {1:"a"}.
+func parseComplex(s string) (interface{}, error) {
+	p := &valueParser{s: s}
+	val, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	if p.i < len(p.s) {
+		return nil, fmt.Errorf("Unexpected %q after complex value", p.s[p.i:])
+	}
+
+	return val, nil
+}
+
+type valueParser struct {
+	s string
+	i int
+}
+
+func (p *valueParser) skipSpace() {
+	for p.i < len(p.s) && strings.ContainsRune(" \t\r\n", rune(p.s[p.i])) {
+		p.i++
+	}
+}
+
+func (p *valueParser) next(c byte) bool {
+	p.skipSpace()
+	if p.i < len(p.s) && p.s[p.i] == c {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *valueParser) value() (interface{}, error) {
+	p.skipSpace()
+	if p.i >= len(p.s) {
+		return nil, fmt.Errorf("Unexpected end of complex value")
+	}
+
+	switch p.s[p.i] {
+	case '[':
+		p.i++
+		var vals []interface{}
+		if p.next(']') {
+			return vals, nil
+		}
+		for {
+			val, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, val)
+
+			if p.next(']') {
+				return vals, nil
+			}
+			if !p.next(',') {
+				return nil, fmt.Errorf("Expected ',' or ']' at offset %d of complex value", p.i)
+			}
+		}
+	case '{':
+		p.i++
+		var fields []field
+		if p.next('}') {
+			return fields, nil
+		}
+		for {
+			key, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			if !p.next(':') {
+				return nil, fmt.Errorf("Expected ':' at offset %d of complex value", p.i)
+			}
+			val, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, field{key, val})
+
+			if p.next('}') {
+				return fields, nil
+			}
+			if !p.next(',') {
+				return nil, fmt.Errorf("Expected ',' or '}' at offset %d of complex value", p.i)
+			}
+		}
+	case '"':
+		start := p.i
+		for p.i++; p.i < len(p.s) && p.s[p.i] != '"'; p.i++ {
+			if p.s[p.i] == '\\' {
+				p.i++
+			}
+		}
+		if p.i >= len(p.s) {
+			return nil, fmt.Errorf("Unterminated string in complex value")
+		}
+		p.i++
+
+		var str string
+		if err := json.Unmarshal([]byte(p.s[start:p.i]), &str); err != nil {
+			return nil, fmt.Errorf("Bad string in complex value: %v", err)
+		}
+		return str, nil
+	default:
+		start := p.i
+		for p.i < len(p.s) && !strings.ContainsRune(",:]} \t\r\n", rune(p.s[p.i])) {
+			p.i++
+		}
+
+		switch token := p.s[start:p.i]; token {
+		case "":
+			return nil, fmt.Errorf("Unexpected %q at offset %d of complex value", p.s[p.i], p.i)
+		case "null":
+			return nil, nil
+		case "true", "false":
+			return token == "true", nil
+		default:
+			return number(token), nil
+		}
+	}
+}
+
+// Append a value to a builder of the arrow type it's mapped to. The value
+// is either as scanned from a RowSet, or part of a parsed complex value.
+func appendValue(b array.Builder, val interface{}) error {
+	if val == nil {
+		b.AppendNull()
+		return nil
+	}
+
+	switch b := b.(type) {
+	case *array.NullBuilder:
+		b.AppendNull()
+	case *array.BooleanBuilder:
+		v, err := toBool(val)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Int8Builder:
+		v, err := toInt(val, 8)
+		if err != nil {
+			return err
+		}
+		b.Append(int8(v))
+	case *array.Int16Builder:
+		v, err := toInt(val, 16)
+		if err != nil {
+			return err
+		}
+		b.Append(int16(v))
+	case *array.Int32Builder:
+		v, err := toInt(val, 32)
+		if err != nil {
+			return err
+		}
+		b.Append(int32(v))
+	case *array.Int64Builder:
+		v, err := toInt(val, 64)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Float32Builder:
+		v, err := toFloat(val, 32)
+		if err != nil {
+			return err
+		}
+		b.Append(float32(v))
+	case *array.Float64Builder:
+		v, err := toFloat(val, 64)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.StringBuilder:
+		b.Append(toString(val))
+	case *array.BinaryBuilder:
+		b.Append([]byte(toString(val)))
+	case *array.Decimal128Builder:
+		typ := b.Type().(*arrow.Decimal128Type)
+		v, err := decimal128.FromString(toString(val), typ.Precision, typ.Scale)
+		if err != nil {
+			return fmt.Errorf("Can't convert %v to %v: %v", val, typ, err)
+		}
+		b.Append(v)
+	case *array.TimestampBuilder:
+		t, err := time.Parse("2006-01-02 15:04:05.999999999", toString(val))
+		if err != nil {
+			return fmt.Errorf("Can't convert %v to a timestamp: %v", val, err)
+		}
+		b.Append(arrow.Timestamp(t.UnixMicro()))
+	case *array.Date32Builder:
+		t, err := time.Parse("2006-01-02", toString(val))
+		if err != nil {
+			return fmt.Errorf("Can't convert %v to a date: %v", val, err)
+		}
+		b.Append(arrow.Date32FromTime(t))
+	case *array.ListBuilder:
+		vals, err := complexValue(val)
+		if err != nil {
+			return err
+		}
+		elems, ok := vals.([]interface{})
+		if vals != nil && !ok {
+			return fmt.Errorf("Can't convert %v to an array", val)
+		}
+
+		b.Append(true)
+		for _, elem := range elems {
+			if err := appendValue(b.ValueBuilder(), elem); err != nil {
+				return err
+			}
+		}
+	case *array.MapBuilder:
+		vals, err := complexValue(val)
+		if err != nil {
+			return err
+		}
+		fields, ok := vals.([]field)
+		if vals != nil && !ok {
+			return fmt.Errorf("Can't convert %v to a map", val)
+		}
+
+		b.Append(true)
+		for _, f := range fields {
+			if err := appendValue(b.KeyBuilder(), f.key); err != nil {
+				return err
+			}
+			if err := appendValue(b.ItemBuilder(), f.value); err != nil {
+				return err
+			}
+		}
+	case *array.StructBuilder:
+		vals, err := complexValue(val)
+		if err != nil {
+			return err
+		}
+		fields, ok := vals.([]field)
+		if vals != nil && !ok {
+			return fmt.Errorf("Can't convert %v to a struct", val)
+		}
+
+		b.Append(true)
+		typ := b.Type().(*arrow.StructType)
+		for i, f := range typ.Fields() {
+			if err := appendValue(b.FieldBuilder(i), structField(fields, f.Name)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("Can't export values of type %v", b.Type())
+	}
+
+	return nil
+}
+
+// Returns a complex value, parsing it if it's hive's text for one.
+func complexValue(val interface{}) (interface{}, error) {
+	if s, ok := val.(string); ok {
+		return parseComplex(s)
+	}
+	return val, nil
+}
+
+// Returns the value of the named field of a struct, or nil if it's absent.
+// Hive lowercases field names, so they're matched case-insensitively.
+func structField(fields []field, name string) interface{} {
+	for _, f := range fields {
+		if key, ok := f.key.(string); ok && strings.EqualFold(key, name) {
+			return f.value
+		}
+	}
+	return nil
+}
+
+func toBool(val interface{}) (bool, error) {
+	switch v := val.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(v)
+	}
+	return false, fmt.Errorf("Can't convert %v to a boolean", val)
+}
+
+func toInt(val interface{}, bits int) (int64, error) {
+	switch v := val.(type) {
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case number:
+		return strconv.ParseInt(string(v), 10, bits)
+	case string:
+		return strconv.ParseInt(v, 10, bits)
+	}
+	return 0, fmt.Errorf("Can't convert %v to an integer", val)
+}
+
+func toFloat(val interface{}, bits int) (float64, error) {
+	switch v := val.(type) {
+	case float64:
+		return v, nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case number:
+		return strconv.ParseFloat(string(v), bits)
+	case string:
+		return strconv.ParseFloat(v, bits)
+	}
+	return 0, fmt.Errorf("Can't convert %v to a float", val)
+}
+
+func toString(val interface{}) string {
+	switch v := val.(type) {
+	case string:
+		return v
+	case number:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
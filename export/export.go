@@ -0,0 +1,274 @@
+// Package export writes the rows of a hivething.RowSet to files in common
+// formats: CSV, TSV and JSON Lines.
+package export
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/derekgr/hivething"
+)
+
+// Options controlling how rows are written.
+type Options struct {
+	// Omit the line of column names from the start of CSV and TSV output.
+	NoHeader bool
+	// Written in place of NULL values in CSV and TSV output, e.g. `\N` as
+	// hive itself writes them. JSON Lines output always uses null.
+	Null string
+	// Compress the output with gzip.
+	Gzip bool
+}
+
+// Writes the rows of a format, one at a time.
+type encoder interface {
+	header(columns []string) error
+	row(columns []string, types []string, vals []interface{}) error
+	flush() error
+}
+
+// Write rows as CSV, quoted as described in RFC 4180, with a header of
+// column names unless options.NoHeader is set. Returns the number of rows
+// written.
+func WriteCSV(w io.Writer, rows hivething.RowSet, options Options) (int64, error) {
+	return write(w, rows, options, func(w io.Writer) encoder {
+		return &csvEncoder{csv.NewWriter(w), options}
+	})
+}
+
+// Write rows as tab-separated values, escaping tabs, newlines and
+// backslashes in values with a backslash, as hive does. There's a header
+// of column names unless options.NoHeader is set. Returns the number of
+// rows written.
+func WriteTSV(w io.Writer, rows hivething.RowSet, options Options) (int64, error) {
+	return write(w, rows, options, func(w io.Writer) encoder {
+		return &tsvEncoder{w, options}
+	})
+}
+
+// Write rows as JSON Lines: a JSON object per row, keyed by column name in
+// column order. Values of complex columns (arrays, maps, structs and
+// unions), which hive sends as JSON, are written as JSON rather than as
+// strings. Returns the number of rows written.
+func WriteJSONLines(w io.Writer, rows hivething.RowSet, options Options) (int64, error) {
+	return write(w, rows, options, func(w io.Writer) encoder {
+		return &jsonEncoder{w}
+	})
+}
+
+func write(w io.Writer, rows hivething.RowSet, options Options, newEncoder func(io.Writer) encoder) (int64, error) {
+	status, err := rows.Wait()
+	if err != nil {
+		return 0, err
+	}
+	if !status.IsSuccess() {
+		return 0, fmt.Errorf("Unsuccessful query execution: %v", status)
+	}
+
+	var gz *gzip.Writer
+	if options.Gzip {
+		gz = gzip.NewWriter(w)
+		w = gz
+	}
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, err
+	}
+
+	columns := rows.Columns()
+	types := make([]string, len(columnTypes))
+	for i, ct := range columnTypes {
+		types[i] = ct.DatabaseTypeName()
+	}
+	enc := newEncoder(w)
+
+	if err := enc.header(columns); err != nil {
+		return 0, fmt.Errorf("Error writing header: %v", err)
+	}
+
+	vals := make([]interface{}, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+
+	var n int64
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, err
+		}
+
+		if err := enc.row(columns, types, vals); err != nil {
+			return n, fmt.Errorf("Error writing row %d: %v", n+1, err)
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+
+	if err := enc.flush(); err != nil {
+		return n, err
+	}
+
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// Returns the text of a value for CSV or TSV output.
+func format(val interface{}, null string) string {
+	switch v := val.(type) {
+	case nil:
+		return null
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type csvEncoder struct {
+	w       *csv.Writer
+	options Options
+}
+
+func (e *csvEncoder) header(columns []string) error {
+	if e.options.NoHeader {
+		return nil
+	}
+	return e.w.Write(columns)
+}
+
+func (e *csvEncoder) row(columns []string, types []string, vals []interface{}) error {
+	record := make([]string, len(vals))
+	for i, val := range vals {
+		record[i] = format(val, e.options.Null)
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvEncoder) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+type tsvEncoder struct {
+	w       io.Writer
+	options Options
+}
+
+func (e *tsvEncoder) header(columns []string) error {
+	if e.options.NoHeader {
+		return nil
+	}
+	return e.writeLine(columns, nil)
+}
+
+func (e *tsvEncoder) row(columns []string, types []string, vals []interface{}) error {
+	fields := make([]string, len(vals))
+	for i, val := range vals {
+		fields[i] = format(val, e.options.Null)
+	}
+	return e.writeLine(fields, vals)
+}
+
+// Write fields as a line, escaping all but the NULL ones. fields is left
+// as it is, since the header's are the RowSet's column names.
+func (e *tsvEncoder) writeLine(fields []string, vals []interface{}) error {
+	escaped := make([]string, len(fields))
+	for i, field := range fields {
+		escaped[i] = field
+		if vals == nil || vals[i] != nil {
+			escaped[i] = tsvEscaper.Replace(field)
+		}
+	}
+
+	_, err := io.WriteString(e.w, strings.Join(escaped, "\t")+"\n")
+	return err
+}
+
+func (e *tsvEncoder) flush() error {
+	return nil
+}
+
+type jsonEncoder struct {
+	w io.Writer
+}
+
+func (e *jsonEncoder) header(columns []string) error {
+	return nil
+}
+
+func (e *jsonEncoder) row(columns []string, types []string, vals []interface{}) error {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, val := range vals {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		name, _ := json.Marshal(columns[i])
+		b.Write(name)
+		b.WriteByte(':')
+
+		var typeName string
+		if i < len(types) {
+			typeName = types[i]
+		}
+
+		value, err := jsonValue(val, typeName)
+		if err != nil {
+			return err
+		}
+		b.Write(value)
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(e.w, b.String())
+	return err
+}
+
+func (e *jsonEncoder) flush() error {
+	return nil
+}
+
+// Returns the JSON encoding of a value from a column of the given type.
+func jsonValue(val interface{}, typeName string) ([]byte, error) {
+	switch v := val.(type) {
+	case string:
+		if isComplex(typeName) && json.Valid([]byte(v)) {
+			return []byte(v), nil
+		}
+	case float64:
+		// JSON has no representation of these, so spell them as strings.
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return json.Marshal(format(v, ""))
+		}
+	}
+
+	return json.Marshal(val)
+}
+
+func isComplex(typeName string) bool {
+	switch typeName {
+	case "ARRAY", "MAP", "STRUCT", "UNIONTYPE":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/derekgr/hivething"
+	"github.com/derekgr/hivething/TCLIService"
+	"github.com/derekgr/hivething/hivetest"
+)
+
+func staticRows() hivething.RowSet {
+	return hivething.NewStaticRowSet([]string{"id", "name", "score"}, [][]interface{}{
+		{int32(1), "plain", 1.5},
+		{int32(2), "has, comma", nil},
+		{int32(3), "has \"quotes\"\tand\ttabs\nand a newline", math.NaN()},
+	})
+}
+
+func TestWriteCSV(t *testing.T) {
+	var out bytes.Buffer
+	n, err := WriteCSV(&out, staticRows(), Options{Null: "NULL"})
+	if err != nil {
+		t.Fatalf("WriteCSV error: %v", err)
+	}
+
+	expected := "id,name,score\n" +
+		"1,plain,1.5\n" +
+		"2,\"has, comma\",NULL\n" +
+		"3,\"has \"\"quotes\"\"\tand\ttabs\nand a newline\",NaN\n"
+	if out.String() != expected {
+		t.Errorf("Expected CSV\n%s\nbut got\n%s", expected, out.String())
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 rows written but got %d", n)
+	}
+}
+
+func TestWriteTSV(t *testing.T) {
+	var out bytes.Buffer
+	if _, err := WriteTSV(&out, staticRows(), Options{NoHeader: true, Null: `\N`}); err != nil {
+		t.Fatalf("WriteTSV error: %v", err)
+	}
+
+	expected := "1\tplain\t1.5\n" +
+		"2\thas, comma\t\\N\n" +
+		"3\thas \"quotes\"\\tand\\ttabs\\nand a newline\tNaN\n"
+	if out.String() != expected {
+		t.Errorf("Expected TSV\n%s\nbut got\n%s", expected, out.String())
+	}
+
+	// Header names are escaped without changing the RowSet's.
+	out.Reset()
+	rows := hivething.NewStaticRowSet([]string{"a\tb"}, [][]interface{}{{1}})
+	if _, err := WriteTSV(&out, rows, Options{}); err != nil {
+		t.Fatalf("WriteTSV error: %v", err)
+	}
+	if out.String() != "a\\tb\n1\n" {
+		t.Errorf("Expected an escaped header but got %q", out.String())
+	}
+	if columns := rows.Columns(); columns[0] != "a\tb" {
+		t.Errorf("Expected the RowSet's columns to be unchanged but got %q", columns)
+	}
+}
+
+func TestWriteJSONLines(t *testing.T) {
+	var out bytes.Buffer
+	if _, err := WriteJSONLines(&out, staticRows(), Options{}); err != nil {
+		t.Fatalf("WriteJSONLines error: %v", err)
+	}
+
+	expected := `{"id":1,"name":"plain","score":1.5}` + "\n" +
+		`{"id":2,"name":"has, comma","score":null}` + "\n" +
+		`{"id":3,"name":"has \"quotes\"\tand\ttabs\nand a newline","score":"NaN"}` + "\n"
+	if out.String() != expected {
+		t.Errorf("Expected JSON Lines\n%s\nbut got\n%s", expected, out.String())
+	}
+}
+
+func TestWriteJSONLinesComplexTypes(t *testing.T) {
+	server, err := hivetest.NewServer()
+	if err != nil {
+		t.Fatalf("Can't start fake server: %v", err)
+	}
+	defer server.Close()
+
+	server.Handle("select * from events").
+		Column("events.tags", tcliservice.TTypeId_ARRAY_TYPE).
+		Column("events.attrs", tcliservice.TTypeId_MAP_TYPE).
+		Column("events.note", tcliservice.TTypeId_STRING_TYPE).
+		Row(`["a","b"]`, `{"k":1}`, `["not","an","array"]`).
+		Row(nil, nil, nil)
+
+	db, err := hivething.Connect(server.Addr(), hivething.Options{BatchSize: 10, PollStrategy: hivething.FixedInterval(time.Millisecond)})
+	if err != nil {
+		t.Fatalf("Connect error: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("select * from events")
+	if err != nil {
+		t.Fatalf("Query error: %v", err)
+	}
+
+	var out bytes.Buffer
+	if _, err := WriteJSONLines(&out, rows, Options{}); err != nil {
+		t.Fatalf("WriteJSONLines error: %v", err)
+	}
+
+	expected := `{"events.tags":["a","b"],"events.attrs":{"k":1},"events.note":"[\"not\",\"an\",\"array\"]"}` + "\n" +
+		`{"events.tags":null,"events.attrs":null,"events.note":null}` + "\n"
+	if out.String() != expected {
+		t.Errorf("Expected JSON Lines\n%s\nbut got\n%s", expected, out.String())
+	}
+}
+
+func TestWriteGzip(t *testing.T) {
+	var out bytes.Buffer
+	if _, err := WriteCSV(&out, staticRows(), Options{Gzip: true}); err != nil {
+		t.Fatalf("WriteCSV error: %v", err)
+	}
+
+	r, err := gzip.NewReader(&out)
+	if err != nil {
+		t.Fatalf("Output isn't gzipped: %v", err)
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Error reading gzipped output: %v", err)
+	}
+
+	if !bytes.HasPrefix(data, []byte("id,name,score\n1,plain,1.5\n")) {
+		t.Errorf("Unexpected uncompressed output %q", data)
+	}
+}
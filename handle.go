@@ -0,0 +1,143 @@
+package hivething
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+	"github.com/derekgr/hivething/TCLIService"
+)
+
+// The version of the handle format RowSet.Handle returns.
+const HandleVersion = 1
+
+// Handles start with this prefix, then the format's version and a dot.
+// Older handles were a raw thrift TOperationHandle, which can't start with
+// it, since a thrift struct starts with a field type byte.
+const handlePrefix = "hth"
+
+// Describes the operation a handle refers to, and where it came from.
+type HandleInfo struct {
+	// The version of the handle's format, or 0 for a raw handle from
+	// before handles were versioned, which records only the operation.
+	Version int
+	// The address of the server the operation was started on, if known.
+	Host string
+	// The session the operation was started in.
+	Session *tcliservice.TSessionHandle
+	// The protocol version the server agreed to for the session.
+	ProtocolVersion tcliservice.TProtocolVersion
+	// The HashQuery of the statement executed; empty for metadata
+	// operations.
+	QueryHash string
+	CreatedAt time.Time
+	Operation *tcliservice.TOperationHandle
+}
+
+// The encoded form of a HandleInfo, less its version.
+type handleEnvelope struct {
+	Host      string    `json:"host,omitempty"`
+	Session   []byte    `json:"session,omitempty"`
+	Protocol  int64     `json:"protocol"`
+	QueryHash string    `json:"query,omitempty"`
+	CreatedAt time.Time `json:"created"`
+	Operation []byte    `json:"operation"`
+}
+
+// Returns a hash identifying a statement, as recorded in a handle, for
+// checking that a handle is for the query expected.
+func HashQuery(statement string) string {
+	sum := sha256.Sum256([]byte(statement))
+	return hex.EncodeToString(sum[:])
+}
+
+// Returns the handle for an operation, as a string of URL-safe characters.
+func (h *HandleInfo) encode() ([]byte, error) {
+	operation, err := serializeOp(h.Operation)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := handleEnvelope{
+		Host:      h.Host,
+		Protocol:  int64(h.ProtocolVersion),
+		QueryHash: h.QueryHash,
+		CreatedAt: h.CreatedAt,
+		Operation: operation,
+	}
+	if h.Session != nil {
+		if envelope.Session, err = thrift.NewTSerializer().Write(h.Session); err != nil {
+			return nil, err
+		}
+	}
+
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := handlePrefix + strconv.Itoa(HandleVersion) + "."
+	handle := make([]byte, len(prefix)+base64.RawURLEncoding.EncodedLen(len(payload)))
+	copy(handle, prefix)
+	base64.RawURLEncoding.Encode(handle[len(prefix):], payload)
+	return handle, nil
+}
+
+// Decode a handle returned by RowSet.Handle, of any version.
+func ParseHandle(handle []byte) (*HandleInfo, error) {
+	if !bytes.HasPrefix(handle, []byte(handlePrefix)) {
+		operation, err := deserializeOp(handle)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid handle: %v", err)
+		}
+		return &HandleInfo{Operation: operation}, nil
+	}
+
+	dot := bytes.IndexByte(handle, '.')
+	if dot < 0 {
+		return nil, fmt.Errorf("Invalid handle: no version")
+	}
+	version, err := strconv.Atoi(string(handle[len(handlePrefix):dot]))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid handle version %q", handle[len(handlePrefix):dot])
+	}
+	if version != HandleVersion {
+		return nil, fmt.Errorf("Unsupported handle version %d; this version of hivething reads version %d", version, HandleVersion)
+	}
+
+	payload := make([]byte, base64.RawURLEncoding.DecodedLen(len(handle)-dot-1))
+	n, err := base64.RawURLEncoding.Decode(payload, handle[dot+1:])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid handle: %v", err)
+	}
+
+	var envelope handleEnvelope
+	if err := json.Unmarshal(payload[:n], &envelope); err != nil {
+		return nil, fmt.Errorf("Invalid handle: %v", err)
+	}
+
+	info := &HandleInfo{
+		Version:         version,
+		Host:            envelope.Host,
+		ProtocolVersion: tcliservice.TProtocolVersion(envelope.Protocol),
+		QueryHash:       envelope.QueryHash,
+		CreatedAt:       envelope.CreatedAt,
+	}
+	if info.Operation, err = deserializeOp(envelope.Operation); err != nil {
+		return nil, fmt.Errorf("Invalid handle: %v", err)
+	}
+	if len(envelope.Session) > 0 {
+		info.Session = &tcliservice.TSessionHandle{}
+		if err := thrift.NewTDeserializer().Read(info.Session, envelope.Session); err != nil {
+			return nil, fmt.Errorf("Invalid handle: %v", err)
+		}
+	}
+
+	return info, nil
+}
@@ -0,0 +1,193 @@
+package hivetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/derekgr/hivething"
+)
+
+// A hivething.Client that answers registered queries with canned results,
+// without any network. Use it to test code that accepts a hivething.Client.
+type MockClient struct {
+	mu       sync.Mutex
+	results  map[string]*MockResult
+	handles  map[string]hivething.RowSet
+	calls    []Call
+	closed   bool
+	nextOpId int
+}
+
+// A call made on a MockClient. Query is the query text, or the metadata
+// method name (e.g. "GetTables") for metadata calls, whose patterns are
+// recorded in Args.
+type Call struct {
+	Query  string
+	Config map[string]string
+	Args   []interface{}
+}
+
+// The canned result of a query registered with MockClient.Expect.
+type MockResult struct {
+	columns []string
+	rows    [][]interface{}
+	err     error
+	calls   int
+}
+
+var _ hivething.Client = (*MockClient)(nil)
+
+func NewMockClient() *MockClient {
+	return &MockClient{
+		results: make(map[string]*MockResult),
+		handles: make(map[string]hivething.RowSet),
+	}
+}
+
+// Register a query, or metadata method name such as "GetTables", that the
+// client should expect. Queries are matched on their text, before any
+// arguments are bound, and may be issued any number of times.
+func (m *MockClient) Expect(query string) *MockResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := &MockResult{}
+	m.results[query] = result
+	return result
+}
+
+// Set the result's column names.
+func (r *MockResult) Columns(names ...string) *MockResult {
+	r.columns = names
+	return r
+}
+
+// Add a row to the result. Values should have the types a hive RowSet
+// produces: string, bool, int32, int64 or float64.
+func (r *MockResult) Row(values ...interface{}) *MockResult {
+	r.rows = append(r.rows, values)
+	return r
+}
+
+// Have the query fail with err instead of returning rows.
+func (r *MockResult) Error(err error) *MockResult {
+	r.err = err
+	return r
+}
+
+// The calls made on the client, in order.
+func (m *MockClient) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+// Returns an error naming any expected queries that were never issued.
+func (m *MockClient) Verify() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var unmet []string
+	for query, result := range m.results {
+		if result.calls == 0 {
+			unmet = append(unmet, query)
+		}
+	}
+
+	if len(unmet) > 0 {
+		return fmt.Errorf("hivetest: expected queries were not issued: %s", strings.Join(unmet, "; "))
+	}
+
+	return nil
+}
+
+func (m *MockClient) call(query string, config map[string]string, args ...interface{}) (hivething.RowSet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls = append(m.calls, Call{query, config, args})
+
+	if m.closed {
+		return nil, errors.New("hivetest: MockClient is closed")
+	}
+
+	result, ok := m.results[query]
+	if !ok {
+		return nil, fmt.Errorf("hivetest: unexpected query %q", query)
+	}
+
+	result.calls++
+	if result.err != nil {
+		return nil, result.err
+	}
+
+	m.nextOpId++
+	rows := &mockRowSet{
+		RowSet: hivething.NewStaticRowSet(result.columns, result.rows),
+		handle: []byte(fmt.Sprintf("mock-operation-%d", m.nextOpId)),
+	}
+	m.handles[string(rows.handle)] = rows
+
+	return rows, nil
+}
+
+func (m *MockClient) Query(query string, args ...interface{}) (hivething.RowSet, error) {
+	return m.call(query, nil, args...)
+}
+
+func (m *MockClient) QueryContext(ctx context.Context, query string, args ...interface{}) (hivething.RowSet, error) {
+	return m.call(query, nil, args...)
+}
+
+func (m *MockClient) QueryWithConfig(query string, config map[string]string, args ...interface{}) (hivething.RowSet, error) {
+	return m.call(query, config, args...)
+}
+
+func (m *MockClient) GetSchemas(schemaPattern string) (hivething.RowSet, error) {
+	return m.call("GetSchemas", nil, schemaPattern)
+}
+
+func (m *MockClient) GetTables(schemaPattern, tablePattern string) (hivething.RowSet, error) {
+	return m.call("GetTables", nil, schemaPattern, tablePattern)
+}
+
+func (m *MockClient) GetColumns(schemaPattern, tablePattern, columnPattern string) (hivething.RowSet, error) {
+	return m.call("GetColumns", nil, schemaPattern, tablePattern, columnPattern)
+}
+
+func (m *MockClient) GetFunctions(schemaPattern, functionPattern string) (hivething.RowSet, error) {
+	return m.call("GetFunctions", nil, schemaPattern, functionPattern)
+}
+
+// Reattach to a RowSet previously returned by this client, from its
+// Handle. Rows already read from it are not returned again.
+func (m *MockClient) Reattach(handle []byte) (hivething.RowSet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rows, ok := m.handles[string(handle)]
+	if !ok {
+		return nil, fmt.Errorf("hivetest: unknown operation handle %q", handle)
+	}
+
+	return rows, nil
+}
+
+func (m *MockClient) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
+
+type mockRowSet struct {
+	hivething.RowSet
+	handle []byte
+}
+
+func (r *mockRowSet) Handle() ([]byte, error) {
+	return r.handle, nil
+}
@@ -0,0 +1,80 @@
+package hivetest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/derekgr/hivething"
+)
+
+// Stands in for application code that depends on hivething.
+func countRows(client hivething.Client, table string) (int64, error) {
+	rows, err := client.Query("SELECT COUNT(*) FROM "+table+" WHERE day = ?", "2014-06-01")
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	for rows.Next() {
+		if err := rows.Scan(&count); err != nil {
+			return 0, err
+		}
+	}
+
+	return count, nil
+}
+
+func TestMockClient(t *testing.T) {
+	client := NewMockClient()
+	client.Expect("SELECT COUNT(*) FROM foo WHERE day = ?").
+		Columns("_c0").
+		Row(int64(3))
+	client.Expect("SELECT COUNT(*) FROM bar WHERE day = ?").
+		Error(errors.New("Table not found"))
+
+	if count, err := countRows(client, "foo"); err != nil || count != 3 {
+		t.Errorf("Expected count of 3 but got %d, %v", count, err)
+	}
+
+	if _, err := countRows(client, "bar"); err == nil {
+		t.Error("Expected the canned error for bar")
+	}
+
+	if _, err := countRows(client, "baz"); err == nil {
+		t.Error("Expected an error for an unexpected query")
+	}
+
+	calls := client.Calls()
+	if len(calls) != 3 || calls[0].Args[0] != "2014-06-01" {
+		t.Errorf("Expected 3 recorded calls with args, but got %+v", calls)
+	}
+
+	if err := client.Verify(); err != nil {
+		t.Errorf("Expected all expectations to be met: %v", err)
+	}
+}
+
+func TestMockClientReattach(t *testing.T) {
+	client := NewMockClient()
+	client.Expect("SHOW TABLES").Columns("tab_name").Row("foo")
+
+	rows, err := client.Query("SHOW TABLES")
+	if err != nil {
+		t.Fatalf("Query error: %v", err)
+	}
+
+	handle, err := rows.Handle()
+	if err != nil {
+		t.Fatalf("Handle error: %v", err)
+	}
+
+	reattached, err := client.Reattach(handle)
+	if err != nil {
+		t.Fatalf("Reattach error: %v", err)
+	}
+
+	var table string
+	if !reattached.Next() || reattached.Scan(&table) != nil || table != "foo" {
+		t.Errorf("Expected to read foo from the reattached RowSet, but got %q", table)
+	}
+}
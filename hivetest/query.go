@@ -0,0 +1,168 @@
+package hivetest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/derekgr/hivething/TCLIService"
+)
+
+// The scripted response to a statement or metadata call. Its methods
+// return the Query so they can be chained, and should all be called
+// before a client issues the statement.
+type Query struct {
+	columns    []*tcliservice.TColumnDesc
+	rows       []*tcliservice.TRow
+	states     []tcliservice.TOperationState
+	progress   []*tcliservice.TProgressUpdateResp
+	executeErr *tcliservice.TStatus
+	// Reported with ERROR_STATE, if set.
+	failure *tcliservice.TGetOperationStatusResp
+	delay   time.Duration
+}
+
+func newQuery() *Query {
+	return &Query{}
+}
+
+// Add a result column with the given name and primitive type.
+func (q *Query) Column(name string, typeId tcliservice.TTypeId) *Query {
+	desc := tcliservice.NewTColumnDesc()
+	desc.ColumnName = name
+	desc.TypeDesc.Types = []*tcliservice.TTypeEntry{{
+		PrimitiveEntry: tcliservice.TPrimitiveTypeEntry{TypeA1: typeId},
+	}}
+
+	return q.ColumnDesc(desc)
+}
+
+// Add a result column described in full, for type qualifiers or comments.
+// Its position is set from the order columns are added.
+func (q *Query) ColumnDesc(desc *tcliservice.TColumnDesc) *Query {
+	desc.Position = int32(len(q.columns) + 1)
+	q.columns = append(q.columns, desc)
+	return q
+}
+
+// Add a result row. Values must be one of bool, int8, int16, int32,
+// int64, float64, string or nil (for NULL), matching the thrift column
+// value a real server would send; Row panics on any other type.
+func (q *Query) Row(values ...interface{}) *Query {
+	row := tcliservice.NewTRow()
+	for _, val := range values {
+		row.ColVals = append(row.ColVals, columnValue(val))
+	}
+
+	q.rows = append(q.rows, row)
+	return q
+}
+
+// Set the operation states reported by successive GetOperationStatus
+// calls. The last state is repeated once the others are used up. The
+// default is to report FINISHED_STATE immediately.
+func (q *Query) States(states ...tcliservice.TOperationState) *Query {
+	q.states = states
+	return q
+}
+
+// Have the operation report RUNNING_STATE for the given number of polls
+// before finishing.
+func (q *Query) RunFor(polls int) *Query {
+	states := make([]tcliservice.TOperationState, 0, polls+1)
+	for i := 0; i < polls; i++ {
+		states = append(states, tcliservice.TOperationState_RUNNING_STATE)
+	}
+
+	return q.States(append(states, tcliservice.TOperationState_FINISHED_STATE)...)
+}
+
+// Set the progress updates reported with successive GetOperationStatus
+// calls that ask for them, as hive 2.3 and later do. The last update is
+// repeated once the others are used up; a nil update reports none. The
+// default is to report none.
+func (q *Query) Progress(updates ...*tcliservice.TProgressUpdateResp) *Query {
+	q.progress = updates
+	return q
+}
+
+// Have the operation report ERROR_STATE after it is started.
+func (q *Query) Fail() *Query {
+	return q.States(tcliservice.TOperationState_ERROR_STATE)
+}
+
+// Have the operation report ERROR_STATE after it is started, with the
+// error details hive 0.13 and later give for a failed operation.
+func (q *Query) FailWith(sqlState string, code int32, msg string) *Query {
+	q.failure = &tcliservice.TGetOperationStatusResp{SqlState: &sqlState, ErrorCode: &code, ErrorMessage: &msg}
+	return q.Fail()
+}
+
+// Reject the statement itself, so ExecuteStatement returns an error
+// status with msg instead of starting an operation, as hive does for
+// statements that don't compile.
+func (q *Query) FailExecute(msg string) *Query {
+	q.executeErr = &tcliservice.TStatus{StatusCode: tcliservice.TStatusCode_ERROR_STATUS, ErrorMessage: &msg}
+	return q
+}
+
+// Delay ExecuteStatement's response by d, as when a statement is slow to
+// compile or is executed synchronously.
+func (q *Query) Delay(d time.Duration) *Query {
+	q.delay = d
+	return q
+}
+
+func (q *Query) schema() *tcliservice.TTableSchema {
+	schema := tcliservice.NewTTableSchema()
+	schema.Columns = q.columns
+	return schema
+}
+
+func (op *operation) state() tcliservice.TOperationState {
+	switch {
+	case op.canceled:
+		return tcliservice.TOperationState_CANCELED_STATE
+	case len(op.query.states) == 0:
+		return tcliservice.TOperationState_FINISHED_STATE
+	case op.polls < len(op.query.states):
+		return op.query.states[op.polls]
+	}
+
+	return op.query.states[len(op.query.states)-1]
+}
+
+func (op *operation) progressUpdate() *tcliservice.TProgressUpdateResp {
+	switch {
+	case len(op.query.progress) == 0:
+		return nil
+	case op.polls < len(op.query.progress):
+		return op.query.progress[op.polls]
+	}
+
+	return op.query.progress[len(op.query.progress)-1]
+}
+
+func columnValue(val interface{}) *tcliservice.TColumnValue {
+	col := tcliservice.NewTColumnValue()
+	switch v := val.(type) {
+	case nil:
+	case bool:
+		col.BoolVal.Value = &v
+	case int8:
+		col.ByteVal.Value = &v
+	case int16:
+		col.I16Val.Value = &v
+	case int32:
+		col.I32Val.Value = &v
+	case int64:
+		col.I64Val.Value = &v
+	case float64:
+		col.DoubleVal.Value = &v
+	case string:
+		col.StringVal.Value = &v
+	default:
+		panic(fmt.Sprintf("hivetest: unsupported column value %v of type %T", val, val))
+	}
+
+	return col
+}
@@ -0,0 +1,363 @@
+package hivetest
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+	"github.com/derekgr/hivething/TCLIService"
+)
+
+// One call in a recorded session. Requests and responses are thrift
+// binary serialized, as operation handles are by hivething.
+type recordedCall struct {
+	Method   string `json:"method"`
+	Request  []byte `json:"request"`
+	Response []byte `json:"response,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// A TCLIService that passes calls through to another client, writing
+// each request and response to w, one JSON object per line. Pass it to
+// hivething.ConnectClient to capture a session against a real server:
+//
+//	client, err := hivething.Dial("staging:10000")
+//	recorder := hivetest.NewRecorder(client, file)
+//	db, err := hivething.ConnectClient(recorder, hivething.DefaultOptions)
+type Recorder struct {
+	client tcliservice.TCLIService
+
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+func NewRecorder(client tcliservice.TCLIService, w io.Writer) *Recorder {
+	return &Recorder{client: client, enc: json.NewEncoder(w)}
+}
+
+// Returns the first error encountered writing the recording, if any.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *Recorder) record(method string, req, resp thrift.TStruct, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+
+	call := recordedCall{Method: method}
+	if call.Request, r.err = serialize(req); r.err != nil {
+		return
+	}
+
+	if err != nil {
+		call.Error = err.Error()
+	} else if call.Response, r.err = serialize(resp); r.err != nil {
+		return
+	}
+
+	r.err = r.enc.Encode(call)
+}
+
+// The subset of testing.TB a Replayer reports divergence to.
+type Reporter interface {
+	Errorf(format string, args ...interface{})
+}
+
+// A TCLIService that answers calls from a session captured by a Recorder,
+// in the order they were recorded, without any network. Pass it to
+// hivething.ConnectClient. Any request that differs from the recorded one
+// is reported to t, and fails with an error.
+type Replayer struct {
+	t Reporter
+
+	mu    sync.Mutex
+	calls []recordedCall
+	next  int
+}
+
+// Read a recording made by a Recorder.
+func NewReplayer(t Reporter, r io.Reader) (*Replayer, error) {
+	var calls []recordedCall
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var call recordedCall
+		if err := dec.Decode(&call); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("Error reading recording: %v", err)
+		}
+		calls = append(calls, call)
+	}
+
+	return &Replayer{t: t, calls: calls}, nil
+}
+
+// Returns an error if any recorded calls have not been replayed.
+func (p *Replayer) Done() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.next < len(p.calls) {
+		return fmt.Errorf("hivetest: %d recorded calls were not replayed, starting with %s", len(p.calls)-p.next, p.calls[p.next].Method)
+	}
+	return nil
+}
+
+func (p *Replayer) replay(method string, req, resp thrift.TStruct) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.next >= len(p.calls) {
+		return p.diverged("unexpected call to %s after the end of the recording", method)
+	}
+
+	call := p.calls[p.next]
+	p.next++
+
+	if call.Method != method {
+		return p.diverged("call %d was to %s, but %s was recorded", p.next, method, call.Method)
+	}
+
+	// Compare after a round trip through the serializer, so that the
+	// live request is normalized the same way the recorded one was.
+	expected := reflect.New(reflect.TypeOf(req).Elem()).Interface().(thrift.TStruct)
+	actual := reflect.New(reflect.TypeOf(req).Elem()).Interface().(thrift.TStruct)
+	if err := deserialize(expected, call.Request); err != nil {
+		return err
+	}
+	if b, err := serialize(req); err != nil {
+		return err
+	} else if err := deserialize(actual, b); err != nil {
+		return err
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		return p.diverged("call %d to %s diverged from the recording:\n  recorded: %v\n  actual:   %v", p.next, method, expected, actual)
+	}
+
+	if call.Error != "" {
+		return errors.New(call.Error)
+	}
+
+	return deserialize(resp, call.Response)
+}
+
+func (p *Replayer) diverged(format string, args ...interface{}) error {
+	err := fmt.Errorf("hivetest: "+format, args...)
+	p.t.Errorf("%v", err)
+	return err
+}
+
+func serialize(msg thrift.TStruct) ([]byte, error) {
+	ser := thrift.NewTSerializer()
+	return ser.Write(msg)
+}
+
+func deserialize(msg thrift.TStruct, b []byte) error {
+	ser := thrift.NewTDeserializer()
+	return ser.Read(msg, b)
+}
+
+func (r *Recorder) OpenSession(req tcliservice.TOpenSessionReq) (tcliservice.TOpenSessionResp, error) {
+	resp, err := r.client.OpenSession(req)
+	r.record("OpenSession", &req, &resp, err)
+	return resp, err
+}
+
+func (r *Recorder) CloseSession(req tcliservice.TCloseSessionReq) (tcliservice.TCloseSessionResp, error) {
+	resp, err := r.client.CloseSession(req)
+	r.record("CloseSession", &req, &resp, err)
+	return resp, err
+}
+
+func (r *Recorder) GetInfo(req tcliservice.TGetInfoReq) (tcliservice.TGetInfoResp, error) {
+	resp, err := r.client.GetInfo(req)
+	r.record("GetInfo", &req, &resp, err)
+	return resp, err
+}
+
+func (r *Recorder) ExecuteStatement(req tcliservice.TExecuteStatementReq) (tcliservice.TExecuteStatementResp, error) {
+	resp, err := r.client.ExecuteStatement(req)
+	r.record("ExecuteStatement", &req, &resp, err)
+	return resp, err
+}
+
+func (r *Recorder) GetTypeInfo(req tcliservice.TGetTypeInfoReq) (tcliservice.TGetTypeInfoResp, error) {
+	resp, err := r.client.GetTypeInfo(req)
+	r.record("GetTypeInfo", &req, &resp, err)
+	return resp, err
+}
+
+func (r *Recorder) GetCatalogs(req tcliservice.TGetCatalogsReq) (tcliservice.TGetCatalogsResp, error) {
+	resp, err := r.client.GetCatalogs(req)
+	r.record("GetCatalogs", &req, &resp, err)
+	return resp, err
+}
+
+func (r *Recorder) GetSchemas(req tcliservice.TGetSchemasReq) (tcliservice.TGetSchemasResp, error) {
+	resp, err := r.client.GetSchemas(req)
+	r.record("GetSchemas", &req, &resp, err)
+	return resp, err
+}
+
+func (r *Recorder) GetTables(req tcliservice.TGetTablesReq) (tcliservice.TGetTablesResp, error) {
+	resp, err := r.client.GetTables(req)
+	r.record("GetTables", &req, &resp, err)
+	return resp, err
+}
+
+func (r *Recorder) GetTableTypes(req tcliservice.TGetTableTypesReq) (tcliservice.TGetTableTypesResp, error) {
+	resp, err := r.client.GetTableTypes(req)
+	r.record("GetTableTypes", &req, &resp, err)
+	return resp, err
+}
+
+func (r *Recorder) GetColumns(req tcliservice.TGetColumnsReq) (tcliservice.TGetColumnsResp, error) {
+	resp, err := r.client.GetColumns(req)
+	r.record("GetColumns", &req, &resp, err)
+	return resp, err
+}
+
+func (r *Recorder) GetFunctions(req tcliservice.TGetFunctionsReq) (tcliservice.TGetFunctionsResp, error) {
+	resp, err := r.client.GetFunctions(req)
+	r.record("GetFunctions", &req, &resp, err)
+	return resp, err
+}
+
+func (r *Recorder) GetOperationStatus(req tcliservice.TGetOperationStatusReq) (tcliservice.TGetOperationStatusResp, error) {
+	resp, err := r.client.GetOperationStatus(req)
+	r.record("GetOperationStatus", &req, &resp, err)
+	return resp, err
+}
+
+func (r *Recorder) CancelOperation(req tcliservice.TCancelOperationReq) (tcliservice.TCancelOperationResp, error) {
+	resp, err := r.client.CancelOperation(req)
+	r.record("CancelOperation", &req, &resp, err)
+	return resp, err
+}
+
+func (r *Recorder) CloseOperation(req tcliservice.TCloseOperationReq) (tcliservice.TCloseOperationResp, error) {
+	resp, err := r.client.CloseOperation(req)
+	r.record("CloseOperation", &req, &resp, err)
+	return resp, err
+}
+
+func (r *Recorder) GetResultSetMetadata(req tcliservice.TGetResultSetMetadataReq) (tcliservice.TGetResultSetMetadataResp, error) {
+	resp, err := r.client.GetResultSetMetadata(req)
+	r.record("GetResultSetMetadata", &req, &resp, err)
+	return resp, err
+}
+
+func (r *Recorder) FetchResults(req tcliservice.TFetchResultsReq) (tcliservice.TFetchResultsResp, error) {
+	resp, err := r.client.FetchResults(req)
+	r.record("FetchResults", &req, &resp, err)
+	return resp, err
+}
+
+func (p *Replayer) OpenSession(req tcliservice.TOpenSessionReq) (tcliservice.TOpenSessionResp, error) {
+	var resp tcliservice.TOpenSessionResp
+	err := p.replay("OpenSession", &req, &resp)
+	return resp, err
+}
+
+func (p *Replayer) CloseSession(req tcliservice.TCloseSessionReq) (tcliservice.TCloseSessionResp, error) {
+	var resp tcliservice.TCloseSessionResp
+	err := p.replay("CloseSession", &req, &resp)
+	return resp, err
+}
+
+func (p *Replayer) GetInfo(req tcliservice.TGetInfoReq) (tcliservice.TGetInfoResp, error) {
+	var resp tcliservice.TGetInfoResp
+	err := p.replay("GetInfo", &req, &resp)
+	return resp, err
+}
+
+func (p *Replayer) ExecuteStatement(req tcliservice.TExecuteStatementReq) (tcliservice.TExecuteStatementResp, error) {
+	var resp tcliservice.TExecuteStatementResp
+	err := p.replay("ExecuteStatement", &req, &resp)
+	return resp, err
+}
+
+func (p *Replayer) GetTypeInfo(req tcliservice.TGetTypeInfoReq) (tcliservice.TGetTypeInfoResp, error) {
+	var resp tcliservice.TGetTypeInfoResp
+	err := p.replay("GetTypeInfo", &req, &resp)
+	return resp, err
+}
+
+func (p *Replayer) GetCatalogs(req tcliservice.TGetCatalogsReq) (tcliservice.TGetCatalogsResp, error) {
+	var resp tcliservice.TGetCatalogsResp
+	err := p.replay("GetCatalogs", &req, &resp)
+	return resp, err
+}
+
+func (p *Replayer) GetSchemas(req tcliservice.TGetSchemasReq) (tcliservice.TGetSchemasResp, error) {
+	var resp tcliservice.TGetSchemasResp
+	err := p.replay("GetSchemas", &req, &resp)
+	return resp, err
+}
+
+func (p *Replayer) GetTables(req tcliservice.TGetTablesReq) (tcliservice.TGetTablesResp, error) {
+	var resp tcliservice.TGetTablesResp
+	err := p.replay("GetTables", &req, &resp)
+	return resp, err
+}
+
+func (p *Replayer) GetTableTypes(req tcliservice.TGetTableTypesReq) (tcliservice.TGetTableTypesResp, error) {
+	var resp tcliservice.TGetTableTypesResp
+	err := p.replay("GetTableTypes", &req, &resp)
+	return resp, err
+}
+
+func (p *Replayer) GetColumns(req tcliservice.TGetColumnsReq) (tcliservice.TGetColumnsResp, error) {
+	var resp tcliservice.TGetColumnsResp
+	err := p.replay("GetColumns", &req, &resp)
+	return resp, err
+}
+
+func (p *Replayer) GetFunctions(req tcliservice.TGetFunctionsReq) (tcliservice.TGetFunctionsResp, error) {
+	var resp tcliservice.TGetFunctionsResp
+	err := p.replay("GetFunctions", &req, &resp)
+	return resp, err
+}
+
+func (p *Replayer) GetOperationStatus(req tcliservice.TGetOperationStatusReq) (tcliservice.TGetOperationStatusResp, error) {
+	var resp tcliservice.TGetOperationStatusResp
+	err := p.replay("GetOperationStatus", &req, &resp)
+	return resp, err
+}
+
+func (p *Replayer) CancelOperation(req tcliservice.TCancelOperationReq) (tcliservice.TCancelOperationResp, error) {
+	var resp tcliservice.TCancelOperationResp
+	err := p.replay("CancelOperation", &req, &resp)
+	return resp, err
+}
+
+func (p *Replayer) CloseOperation(req tcliservice.TCloseOperationReq) (tcliservice.TCloseOperationResp, error) {
+	var resp tcliservice.TCloseOperationResp
+	err := p.replay("CloseOperation", &req, &resp)
+	return resp, err
+}
+
+func (p *Replayer) GetResultSetMetadata(req tcliservice.TGetResultSetMetadataReq) (tcliservice.TGetResultSetMetadataResp, error) {
+	var resp tcliservice.TGetResultSetMetadataResp
+	err := p.replay("GetResultSetMetadata", &req, &resp)
+	return resp, err
+}
+
+func (p *Replayer) FetchResults(req tcliservice.TFetchResultsReq) (tcliservice.TFetchResultsResp, error) {
+	var resp tcliservice.TFetchResultsResp
+	err := p.replay("FetchResults", &req, &resp)
+	return resp, err
+}
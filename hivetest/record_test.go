@@ -0,0 +1,127 @@
+package hivetest
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/derekgr/hivething"
+	"github.com/derekgr/hivething/TCLIService"
+)
+
+var testOptions = hivething.Options{BatchSize: 2, PollStrategy: hivething.FixedInterval(time.Millisecond)}
+
+func readValues(t *testing.T, db *hivething.Connection, query string) []string {
+	rows, err := db.Query(query)
+	if err != nil {
+		t.Fatalf("Query error: %v", err)
+	}
+
+	if _, err := rows.Wait(); err != nil {
+		t.Fatalf("Wait error: %v", err)
+	}
+
+	var (
+		vals  []string
+		value string
+	)
+	for rows.Next() {
+		rows.Scan(&value)
+		vals = append(vals, value)
+	}
+
+	return vals
+}
+
+func record(t *testing.T) *bytes.Buffer {
+	server, err := NewServer()
+	if err != nil {
+		t.Fatalf("Can't start fake server: %v", err)
+	}
+	defer server.Close()
+
+	server.Handle("select val from foo").
+		Column("foo.val", tcliservice.TTypeId_STRING_TYPE).
+		Row("foo").
+		Row("bar").
+		Row("baz").
+		RunFor(3)
+
+	client, err := hivething.Dial(server.Addr())
+	if err != nil {
+		t.Fatalf("Dial error: %v", err)
+	}
+
+	var recording bytes.Buffer
+	recorder := NewRecorder(client, &recording)
+
+	db, err := hivething.ConnectClient(recorder, testOptions)
+	if err != nil {
+		t.Fatalf("ConnectClient error: %v", err)
+	}
+
+	readValues(t, db, "select val from foo")
+	db.Close()
+
+	if err := recorder.Err(); err != nil {
+		t.Fatalf("Recording error: %v", err)
+	}
+
+	return &recording
+}
+
+func TestRecordReplay(t *testing.T) {
+	replayer, err := NewReplayer(t, record(t))
+	if err != nil {
+		t.Fatalf("NewReplayer error: %v", err)
+	}
+
+	db, err := hivething.ConnectClient(replayer, testOptions)
+	if err != nil {
+		t.Fatalf("ConnectClient error: %v", err)
+	}
+
+	vals := readValues(t, db, "select val from foo")
+	if !reflect.DeepEqual(vals, []string{"foo", "bar", "baz"}) {
+		t.Errorf("Expected replayed rows [foo bar baz] but got %v", vals)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Errorf("Close error: %v", err)
+	}
+
+	if err := replayer.Done(); err != nil {
+		t.Error(err)
+	}
+}
+
+type recordingReporter struct {
+	errors []string
+}
+
+func (r *recordingReporter) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestReplayDivergence(t *testing.T) {
+	reporter := &recordingReporter{}
+	replayer, err := NewReplayer(reporter, record(t))
+	if err != nil {
+		t.Fatalf("NewReplayer error: %v", err)
+	}
+
+	db, err := hivething.ConnectClient(replayer, testOptions)
+	if err != nil {
+		t.Fatalf("ConnectClient error: %v", err)
+	}
+
+	if _, err := db.Query("select id from foo"); err == nil {
+		t.Error("Expected a divergent query to fail")
+	}
+
+	if len(reporter.errors) != 1 {
+		t.Errorf("Expected divergence to be reported once, but got %v", reporter.errors)
+	}
+}
@@ -0,0 +1,516 @@
+// Package hivetest runs an in-process fake hiveserver2, so that code built
+// on hivething can be tested without a real hive installation. Responses
+// are scripted per statement with Handle:
+//
+//	server, err := hivetest.NewServer()
+//	...
+//	defer server.Close()
+//
+//	server.Handle("SELECT * FROM foo").
+//		Column("foo.id", tcliservice.TTypeId_INT_TYPE).
+//		Column("foo.val", tcliservice.TTypeId_STRING_TYPE).
+//		Row(int32(1), "foo").
+//		Row(int32(2), "bar")
+//
+//	db, err := hivething.Connect(server.Addr(), hivething.DefaultOptions)
+//
+// For code that accepts a hivething.Client, MockClient answers registered
+// queries with canned results without any network at all.
+package hivetest
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+	"github.com/derekgr/hivething/TCLIService"
+)
+
+// A fake hiveserver2 listening on a local port.
+type Server struct {
+	listener  net.Listener
+	processor thrift.TProcessor
+
+	mu         sync.Mutex
+	conns      map[net.Conn]bool
+	latency    time.Duration
+	queries    map[string]*Query
+	metadata   map[string]*Query
+	failures   map[string][]error
+	sessions   map[string]bool
+	operations map[string]*operation
+	executed   []tcliservice.TExecuteStatementReq
+}
+
+// An operation the server has started, and how far the client has got
+// through its scripted states and rows.
+type operation struct {
+	handle   tcliservice.TOperationHandle
+	query    *Query
+	polls    int
+	offset   int
+	canceled bool
+}
+
+// Start a fake hiveserver2 on a random local port.
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		listener:   listener,
+		conns:      make(map[net.Conn]bool),
+		queries:    make(map[string]*Query),
+		metadata:   make(map[string]*Query),
+		failures:   make(map[string][]error),
+		sessions:   make(map[string]bool),
+		operations: make(map[string]*operation),
+	}
+	s.processor = tcliservice.NewTCLIServiceProcessor(s)
+
+	go s.serve()
+
+	return s, nil
+}
+
+// The host:port the server is listening on, suitable for hivething.Connect.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Stop listening and disconnect all clients.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+
+	return err
+}
+
+// Script the response to a statement, which must match the text sent by
+// the client exactly. Registering the same statement again replaces it.
+func (s *Server) Handle(statement string) *Query {
+	q := newQuery()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries[statement] = q
+
+	return q
+}
+
+// Script the operation started by a metadata call, such as "GetTables" or
+// "GetColumns", whose request arguments are otherwise ignored.
+func (s *Server) HandleMetadata(call string) *Query {
+	q := newQuery()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metadata[call] = q
+
+	return q
+}
+
+// Delay every response by d, to simulate network and server latency.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// Make the next call to the named thrift method (e.g. "FetchResults") fail
+// with err, which the client receives as a thrift application exception.
+// Failures queue up, one per call, if FailNext is called repeatedly.
+func (s *Server) FailNext(method string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[method] = append(s.failures[method], err)
+}
+
+// Every ExecuteStatement request received, in order.
+func (s *Server) Executed() []tcliservice.TExecuteStatementReq {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]tcliservice.TExecuteStatementReq(nil), s.executed...)
+}
+
+// Forget a session, as hiveserver2 does when it times out idle sessions,
+// so further calls on it fail with an invalid handle status.
+func (s *Server) ExpireSession(session tcliservice.TSessionHandle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, string(session.SessionId.Guid))
+}
+
+// The number of sessions currently open.
+func (s *Server) OpenSessions() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+// The number of operations started and not yet closed.
+func (s *Server) OpenOperations() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.operations)
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = true
+		s.mu.Unlock()
+
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+
+	transport := thrift.NewTBufferedTransport(thrift.NewTSocketFromConnTimeout(conn, 0), 4096)
+	protocol := thrift.NewTBinaryProtocolFactoryDefault()
+	in, out := protocol.GetProtocol(transport), protocol.GetProtocol(transport)
+
+	for {
+		if ok, err := s.processor.Process(in, out); !ok {
+			// Scripted failures have already been sent to the client as
+			// exceptions; anything else means the connection is unusable.
+			if _, scripted := err.(scriptedError); !scripted {
+				return
+			}
+		}
+	}
+}
+
+type scriptedError struct {
+	error
+}
+
+// Called at the start of every RPC, with the lock held, to apply latency
+// and any scripted failure for the method.
+func (s *Server) begin(method string) error {
+	if s.latency > 0 {
+		latency := s.latency
+		s.mu.Unlock()
+		time.Sleep(latency)
+		s.mu.Lock()
+	}
+
+	if errs := s.failures[method]; len(errs) > 0 {
+		s.failures[method] = errs[1:]
+		return scriptedError{errs[0]}
+	}
+
+	return nil
+}
+
+func newHandleIdentifier() tcliservice.THandleIdentifier {
+	guid := make([]byte, 16)
+	secret := make([]byte, 16)
+	rand.Read(guid)
+	rand.Read(secret)
+	return tcliservice.THandleIdentifier{Guid: guid, Secret: secret}
+}
+
+func success() tcliservice.TStatus {
+	return tcliservice.TStatus{StatusCode: tcliservice.TStatusCode_SUCCESS_STATUS}
+}
+
+func failure(code tcliservice.TStatusCode, format string, args ...interface{}) tcliservice.TStatus {
+	msg := fmt.Sprintf(format, args...)
+	return tcliservice.TStatus{StatusCode: code, ErrorMessage: &msg}
+}
+
+func (s *Server) validSession(session tcliservice.TSessionHandle) bool {
+	return s.sessions[string(session.SessionId.Guid)]
+}
+
+// Starts an operation for the scripted query q, returning the response
+// status and handle.
+func (s *Server) startOperation(opType tcliservice.TOperationType, q *Query) (tcliservice.TStatus, *tcliservice.TOperationHandle) {
+	if q.executeErr != nil {
+		return *q.executeErr, nil
+	}
+
+	op := &operation{
+		handle: tcliservice.TOperationHandle{
+			OperationId:   newHandleIdentifier(),
+			OperationType: opType,
+			HasResultSet:  len(q.columns) > 0,
+		},
+		query: q,
+	}
+	s.operations[string(op.handle.OperationId.Guid)] = op
+
+	handle := op.handle
+	return success(), &handle
+}
+
+func (s *Server) lookupOperation(handle tcliservice.TOperationHandle) (*operation, tcliservice.TStatus) {
+	op, ok := s.operations[string(handle.OperationId.Guid)]
+	if !ok {
+		return nil, failure(tcliservice.TStatusCode_INVALID_HANDLE_STATUS, "Invalid OperationHandle: %x", handle.OperationId.Guid)
+	}
+	return op, success()
+}
+
+func (s *Server) OpenSession(req tcliservice.TOpenSessionReq) (tcliservice.TOpenSessionResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("OpenSession"); err != nil {
+		return tcliservice.TOpenSessionResp{}, err
+	}
+
+	session := &tcliservice.TSessionHandle{SessionId: newHandleIdentifier()}
+	s.sessions[string(session.SessionId.Guid)] = true
+
+	return tcliservice.TOpenSessionResp{
+		Status:                success(),
+		ServerProtocolVersion: req.ClientProtocol,
+		SessionHandle:         session,
+		Configuration:         req.Configuration,
+	}, nil
+}
+
+func (s *Server) CloseSession(req tcliservice.TCloseSessionReq) (tcliservice.TCloseSessionResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("CloseSession"); err != nil {
+		return tcliservice.TCloseSessionResp{}, err
+	}
+
+	if !s.validSession(req.SessionHandle) {
+		return tcliservice.TCloseSessionResp{Status: failure(tcliservice.TStatusCode_INVALID_HANDLE_STATUS, "Invalid SessionHandle")}, nil
+	}
+	delete(s.sessions, string(req.SessionHandle.SessionId.Guid))
+
+	return tcliservice.TCloseSessionResp{Status: success()}, nil
+}
+
+func (s *Server) GetInfo(req tcliservice.TGetInfoReq) (tcliservice.TGetInfoResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("GetInfo"); err != nil {
+		return tcliservice.TGetInfoResp{}, err
+	}
+
+	if !s.validSession(req.SessionHandle) {
+		return tcliservice.TGetInfoResp{Status: failure(tcliservice.TStatusCode_INVALID_HANDLE_STATUS, "Invalid SessionHandle")}, nil
+	}
+
+	var value tcliservice.TGetInfoValue
+	switch req.InfoType {
+	case tcliservice.TGetInfoType_CLI_SERVER_NAME, tcliservice.TGetInfoType_CLI_DBMS_NAME:
+		value.StringValue = "hivetest"
+	case tcliservice.TGetInfoType_CLI_DBMS_VER:
+		value.StringValue = "0.13.0"
+	}
+
+	return tcliservice.TGetInfoResp{Status: success(), InfoValue: value}, nil
+}
+
+func (s *Server) ExecuteStatement(req tcliservice.TExecuteStatementReq) (tcliservice.TExecuteStatementResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("ExecuteStatement"); err != nil {
+		return tcliservice.TExecuteStatementResp{}, err
+	}
+
+	s.executed = append(s.executed, req)
+
+	if !s.validSession(req.SessionHandle) {
+		return tcliservice.TExecuteStatementResp{Status: failure(tcliservice.TStatusCode_INVALID_HANDLE_STATUS, "Invalid SessionHandle")}, nil
+	}
+
+	q, ok := s.queries[req.Statement]
+	if !ok {
+		return tcliservice.TExecuteStatementResp{Status: failure(tcliservice.TStatusCode_ERROR_STATUS, "hivetest: no response scripted for statement %q", req.Statement)}, nil
+	}
+
+	if q.delay > 0 {
+		s.mu.Unlock()
+		time.Sleep(q.delay)
+		s.mu.Lock()
+	}
+
+	status, handle := s.startOperation(tcliservice.TOperationType_EXECUTE_STATEMENT, q)
+	return tcliservice.TExecuteStatementResp{Status: status, OperationHandle: handle}, nil
+}
+
+// Shared by the metadata calls, which all start an operation for the
+// query scripted with HandleMetadata.
+func (s *Server) metadataOperation(call string, opType tcliservice.TOperationType, session tcliservice.TSessionHandle) (tcliservice.TStatus, *tcliservice.TOperationHandle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin(call); err != nil {
+		return tcliservice.TStatus{}, nil, err
+	}
+
+	if !s.validSession(session) {
+		return failure(tcliservice.TStatusCode_INVALID_HANDLE_STATUS, "Invalid SessionHandle"), nil, nil
+	}
+
+	q, ok := s.metadata[call]
+	if !ok {
+		return failure(tcliservice.TStatusCode_ERROR_STATUS, "hivetest: no response scripted for %s", call), nil, nil
+	}
+
+	status, handle := s.startOperation(opType, q)
+	return status, handle, nil
+}
+
+func (s *Server) GetTypeInfo(req tcliservice.TGetTypeInfoReq) (tcliservice.TGetTypeInfoResp, error) {
+	status, handle, err := s.metadataOperation("GetTypeInfo", tcliservice.TOperationType_GET_TYPE_INFO, req.SessionHandle)
+	return tcliservice.TGetTypeInfoResp{Status: status, OperationHandle: handle}, err
+}
+
+func (s *Server) GetCatalogs(req tcliservice.TGetCatalogsReq) (tcliservice.TGetCatalogsResp, error) {
+	status, handle, err := s.metadataOperation("GetCatalogs", tcliservice.TOperationType_GET_CATALOGS, req.SessionHandle)
+	return tcliservice.TGetCatalogsResp{Status: status, OperationHandle: handle}, err
+}
+
+func (s *Server) GetSchemas(req tcliservice.TGetSchemasReq) (tcliservice.TGetSchemasResp, error) {
+	status, handle, err := s.metadataOperation("GetSchemas", tcliservice.TOperationType_GET_SCHEMAS, req.SessionHandle)
+	return tcliservice.TGetSchemasResp{Status: status, OperationHandle: handle}, err
+}
+
+func (s *Server) GetTables(req tcliservice.TGetTablesReq) (tcliservice.TGetTablesResp, error) {
+	status, handle, err := s.metadataOperation("GetTables", tcliservice.TOperationType_GET_TABLES, req.SessionHandle)
+	return tcliservice.TGetTablesResp{Status: status, OperationHandle: handle}, err
+}
+
+func (s *Server) GetTableTypes(req tcliservice.TGetTableTypesReq) (tcliservice.TGetTableTypesResp, error) {
+	status, handle, err := s.metadataOperation("GetTableTypes", tcliservice.TOperationType_GET_TABLE_TYPES, req.SessionHandle)
+	return tcliservice.TGetTableTypesResp{Status: status, OperationHandle: handle}, err
+}
+
+func (s *Server) GetColumns(req tcliservice.TGetColumnsReq) (tcliservice.TGetColumnsResp, error) {
+	status, handle, err := s.metadataOperation("GetColumns", tcliservice.TOperationType_GET_COLUMNS, req.SessionHandle)
+	return tcliservice.TGetColumnsResp{Status: status, OperationHandle: handle}, err
+}
+
+func (s *Server) GetFunctions(req tcliservice.TGetFunctionsReq) (tcliservice.TGetFunctionsResp, error) {
+	status, handle, err := s.metadataOperation("GetFunctions", tcliservice.TOperationType_GET_FUNCTIONS, req.SessionHandle)
+	return tcliservice.TGetFunctionsResp{Status: status, OperationHandle: handle}, err
+}
+
+func (s *Server) GetOperationStatus(req tcliservice.TGetOperationStatusReq) (tcliservice.TGetOperationStatusResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("GetOperationStatus"); err != nil {
+		return tcliservice.TGetOperationStatusResp{}, err
+	}
+
+	op, status := s.lookupOperation(req.OperationHandle)
+	if op == nil {
+		return tcliservice.TGetOperationStatusResp{Status: status}, nil
+	}
+
+	state := op.state()
+	resp := tcliservice.TGetOperationStatusResp{Status: status, OperationState: &state}
+	if req.GetGetProgressUpdate() {
+		resp.ProgressUpdateResponse = op.progressUpdate()
+	}
+	op.polls++
+
+	if failure := op.query.failure; failure != nil && state == tcliservice.TOperationState_ERROR_STATE {
+		resp.SqlState, resp.ErrorCode, resp.ErrorMessage = failure.SqlState, failure.ErrorCode, failure.ErrorMessage
+	}
+	return resp, nil
+}
+
+func (s *Server) CancelOperation(req tcliservice.TCancelOperationReq) (tcliservice.TCancelOperationResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("CancelOperation"); err != nil {
+		return tcliservice.TCancelOperationResp{}, err
+	}
+
+	op, status := s.lookupOperation(req.OperationHandle)
+	if op != nil {
+		op.canceled = true
+	}
+
+	return tcliservice.TCancelOperationResp{Status: status}, nil
+}
+
+func (s *Server) CloseOperation(req tcliservice.TCloseOperationReq) (tcliservice.TCloseOperationResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("CloseOperation"); err != nil {
+		return tcliservice.TCloseOperationResp{}, err
+	}
+
+	op, status := s.lookupOperation(req.OperationHandle)
+	if op != nil {
+		delete(s.operations, string(req.OperationHandle.OperationId.Guid))
+	}
+
+	return tcliservice.TCloseOperationResp{Status: status}, nil
+}
+
+func (s *Server) GetResultSetMetadata(req tcliservice.TGetResultSetMetadataReq) (tcliservice.TGetResultSetMetadataResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("GetResultSetMetadata"); err != nil {
+		return tcliservice.TGetResultSetMetadataResp{}, err
+	}
+
+	op, status := s.lookupOperation(req.OperationHandle)
+	if op == nil {
+		return tcliservice.TGetResultSetMetadataResp{Status: status}, nil
+	}
+
+	return tcliservice.TGetResultSetMetadataResp{Status: status, Schema: op.query.schema()}, nil
+}
+
+func (s *Server) FetchResults(req tcliservice.TFetchResultsReq) (tcliservice.TFetchResultsResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("FetchResults"); err != nil {
+		return tcliservice.TFetchResultsResp{}, err
+	}
+
+	op, status := s.lookupOperation(req.OperationHandle)
+	if op == nil {
+		return tcliservice.TFetchResultsResp{Status: status}, nil
+	}
+
+	if req.Orientation == tcliservice.TFetchOrientation_FETCH_FIRST {
+		op.offset = 0
+	}
+
+	end := len(op.query.rows)
+	if req.MaxRows > 0 && int64(end-op.offset) > req.MaxRows {
+		end = op.offset + int(req.MaxRows)
+	}
+
+	rowSet := tcliservice.NewTRowSet()
+	rowSet.StartRowOffset = int64(op.offset)
+	rowSet.Rows = op.query.rows[op.offset:end]
+	op.offset = end
+
+	hasMore := op.offset < len(op.query.rows)
+	return tcliservice.TFetchResultsResp{Status: status, HasMoreRows: &hasMore, Results: rowSet}, nil
+}
@@ -0,0 +1,78 @@
+package hivething
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/derekgr/hivething/TCLIService"
+)
+
+// Implemented by every TCLIService response.
+type statusResponse interface {
+	GetStatus() tcliservice.TStatus
+}
+
+// Implemented by requests concerning an operation, and responses to
+// those starting one.
+type operationMessage interface {
+	GetOperationHandle() tcliservice.TOperationHandle
+}
+
+// Returns Middleware logging every call at debug level, with its
+// duration, the ID of the operation it concerns, and any error, and
+// reporting it to metrics. Either may be nil.
+func instrument(logger Logger, metrics Metrics) Middleware {
+	return func(call *Call, next func() error) error {
+		start := time.Now()
+		err := next()
+		duration := time.Since(start)
+
+		failure := err
+		if resp, ok := call.Response.(statusResponse); ok && err == nil && !isSuccessStatus(resp.GetStatus()) {
+			failure = newServerError(call.Method, resp.GetStatus())
+		}
+
+		if metrics != nil {
+			metrics.RPC(call.Method, duration, failure)
+		}
+		if logger == nil {
+			return err
+		}
+
+		args := []interface{}{"method", call.Method, "duration", duration}
+		if id := callOperationID(call, err); id != "" {
+			args = append(args, "operation", id)
+		}
+		if _, ok := failure.(*ServerError); ok {
+			args = append(args, "status", failure.Error())
+		} else if failure != nil {
+			args = append(args, "error", failure)
+		}
+
+		logger.Debug("hive rpc", args...)
+		return err
+	}
+}
+
+// Returns the ID of the operation a call concerns, from its request, or
+// from its response if it started one, or "" if there isn't one.
+func callOperationID(call *Call, err error) string {
+	if req, ok := call.Request.(operationMessage); ok {
+		operation := req.GetOperationHandle()
+		return operationID(&operation)
+	}
+	if resp, ok := call.Response.(operationMessage); ok && err == nil {
+		operation := resp.GetOperationHandle()
+		return operationID(&operation)
+	}
+
+	return ""
+}
+
+// Returns the hex GUID identifying an operation, or "" if there isn't one.
+func operationID(operation *tcliservice.TOperationHandle) string {
+	if operation == nil {
+		return ""
+	}
+	return hex.EncodeToString(operation.OperationId.Guid)
+}
@@ -139,3 +139,52 @@ func TestReattach(t *testing.T) {
 		t.Errorf("Expected 3 row values to be [foo, bar, baz] but was %v", vals)
 	}
 }
+
+func TestSubmit(t *testing.T) {
+	db, err := Connect("127.0.0.1:10000", DefaultOptions)
+	if err != nil {
+		t.Fatalf("Connect error: %v", err)
+	}
+	defer db.Close()
+
+	rows, done, err := db.SubmitChan("select * from foo")
+	if err != nil {
+		t.Fatalf("Connection.SubmitChan error: %v", err)
+	}
+
+	completion := <-done
+	if completion.Error != nil {
+		t.Fatalf("Submitted query failed: %v", completion.Error)
+	}
+
+	if !completion.Status.IsSuccess() {
+		t.Fatalf("Unsuccessful query execution: %v", completion.Status)
+	}
+
+	col := rows.Columns()
+	if !reflect.DeepEqual(col, []string{"foo.id", "foo.val"}) {
+		t.Fatalf("Expected 'id' and 'value' columns, but got %v", col)
+	}
+}
+
+func TestQueryWithConfig(t *testing.T) {
+	db, err := Connect("127.0.0.1:10000", DefaultOptions)
+	if err != nil {
+		t.Fatalf("Connect error: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.QueryWithConfig("select * from foo", map[string]string{"hive.exec.reducers.max": "1"})
+	if err != nil {
+		t.Fatalf("Connection.QueryWithConfig error: %v", err)
+	}
+
+	status, err := rows.Wait()
+	if err != nil {
+		t.Fatalf("Connection.Wait error: %v", err)
+	}
+
+	if !status.IsSuccess() {
+		t.Fatalf("Unsuccessful query execution: %v", status)
+	}
+}
@@ -0,0 +1,190 @@
+package hivething
+
+import (
+	"github.com/derekgr/hivething/TCLIService"
+)
+
+// A call to the server, as seen by Middleware.
+type Call struct {
+	// The TCLIService method called, such as "ExecuteStatement".
+	Method string
+	// A pointer to the request, such as a
+	// *tcliservice.TExecuteStatementReq. Middleware may change it before
+	// making the call, as to rewrite a statement.
+	Request interface{}
+	// A pointer to the response, such as a
+	// *tcliservice.TExecuteStatementResp, which is set once the call is
+	// made. Middleware may change it afterwards.
+	Response interface{}
+}
+
+// Wraps every call a Connection makes to the server, and those made by
+// its RowSets, to audit, rewrite, rate limit or fail them. Middleware calls
+// next to make the call, or the next Middleware's, and returns its error,
+// or returns without calling next to fail the call.
+type Middleware func(call *Call, next func() error) error
+
+// Passes every thrift call made through it through a chain of Middleware.
+type interceptedClient struct {
+	client tcliservice.TCLIService
+	chain  []Middleware
+}
+
+// Wraps client in options.Middleware, the first outermost, and within
+// them, Middleware logging calls to options.Logger and measuring them for
+// options.Metrics, so that those see calls as they're made to the server.
+func newInterceptedClient(client tcliservice.TCLIService, options Options) tcliservice.TCLIService {
+	chain := append([]Middleware(nil), options.Middleware...)
+	if options.Logger != nil || options.Metrics != nil {
+		chain = append(chain, instrument(options.Logger, options.Metrics))
+	}
+
+	if len(chain) == 0 {
+		return client
+	}
+	return &interceptedClient{client: client, chain: chain}
+}
+
+// Makes a call through the chain.
+func (c *interceptedClient) invoke(method string, req, resp interface{}, f func() error) error {
+	return c.run(&Call{Method: method, Request: req, Response: resp}, 0, f)
+}
+
+// Passes call through the chain from the ith Middleware on, and then makes it.
+func (c *interceptedClient) run(call *Call, i int, f func() error) error {
+	if i == len(c.chain) {
+		return f()
+	}
+
+	return c.chain[i](call, func() error {
+		return c.run(call, i+1, f)
+	})
+}
+
+func (c *interceptedClient) OpenSession(req tcliservice.TOpenSessionReq) (resp tcliservice.TOpenSessionResp, err error) {
+	err = c.invoke("OpenSession", &req, &resp, func() (err error) {
+		resp, err = c.client.OpenSession(req)
+		return err
+	})
+	return resp, err
+}
+
+func (c *interceptedClient) CloseSession(req tcliservice.TCloseSessionReq) (resp tcliservice.TCloseSessionResp, err error) {
+	err = c.invoke("CloseSession", &req, &resp, func() (err error) {
+		resp, err = c.client.CloseSession(req)
+		return err
+	})
+	return resp, err
+}
+
+func (c *interceptedClient) GetInfo(req tcliservice.TGetInfoReq) (resp tcliservice.TGetInfoResp, err error) {
+	err = c.invoke("GetInfo", &req, &resp, func() (err error) {
+		resp, err = c.client.GetInfo(req)
+		return err
+	})
+	return resp, err
+}
+
+func (c *interceptedClient) ExecuteStatement(req tcliservice.TExecuteStatementReq) (resp tcliservice.TExecuteStatementResp, err error) {
+	err = c.invoke("ExecuteStatement", &req, &resp, func() (err error) {
+		resp, err = c.client.ExecuteStatement(req)
+		return err
+	})
+	return resp, err
+}
+
+func (c *interceptedClient) GetTypeInfo(req tcliservice.TGetTypeInfoReq) (resp tcliservice.TGetTypeInfoResp, err error) {
+	err = c.invoke("GetTypeInfo", &req, &resp, func() (err error) {
+		resp, err = c.client.GetTypeInfo(req)
+		return err
+	})
+	return resp, err
+}
+
+func (c *interceptedClient) GetCatalogs(req tcliservice.TGetCatalogsReq) (resp tcliservice.TGetCatalogsResp, err error) {
+	err = c.invoke("GetCatalogs", &req, &resp, func() (err error) {
+		resp, err = c.client.GetCatalogs(req)
+		return err
+	})
+	return resp, err
+}
+
+func (c *interceptedClient) GetSchemas(req tcliservice.TGetSchemasReq) (resp tcliservice.TGetSchemasResp, err error) {
+	err = c.invoke("GetSchemas", &req, &resp, func() (err error) {
+		resp, err = c.client.GetSchemas(req)
+		return err
+	})
+	return resp, err
+}
+
+func (c *interceptedClient) GetTables(req tcliservice.TGetTablesReq) (resp tcliservice.TGetTablesResp, err error) {
+	err = c.invoke("GetTables", &req, &resp, func() (err error) {
+		resp, err = c.client.GetTables(req)
+		return err
+	})
+	return resp, err
+}
+
+func (c *interceptedClient) GetTableTypes(req tcliservice.TGetTableTypesReq) (resp tcliservice.TGetTableTypesResp, err error) {
+	err = c.invoke("GetTableTypes", &req, &resp, func() (err error) {
+		resp, err = c.client.GetTableTypes(req)
+		return err
+	})
+	return resp, err
+}
+
+func (c *interceptedClient) GetColumns(req tcliservice.TGetColumnsReq) (resp tcliservice.TGetColumnsResp, err error) {
+	err = c.invoke("GetColumns", &req, &resp, func() (err error) {
+		resp, err = c.client.GetColumns(req)
+		return err
+	})
+	return resp, err
+}
+
+func (c *interceptedClient) GetFunctions(req tcliservice.TGetFunctionsReq) (resp tcliservice.TGetFunctionsResp, err error) {
+	err = c.invoke("GetFunctions", &req, &resp, func() (err error) {
+		resp, err = c.client.GetFunctions(req)
+		return err
+	})
+	return resp, err
+}
+
+func (c *interceptedClient) GetOperationStatus(req tcliservice.TGetOperationStatusReq) (resp tcliservice.TGetOperationStatusResp, err error) {
+	err = c.invoke("GetOperationStatus", &req, &resp, func() (err error) {
+		resp, err = c.client.GetOperationStatus(req)
+		return err
+	})
+	return resp, err
+}
+
+func (c *interceptedClient) CancelOperation(req tcliservice.TCancelOperationReq) (resp tcliservice.TCancelOperationResp, err error) {
+	err = c.invoke("CancelOperation", &req, &resp, func() (err error) {
+		resp, err = c.client.CancelOperation(req)
+		return err
+	})
+	return resp, err
+}
+
+func (c *interceptedClient) CloseOperation(req tcliservice.TCloseOperationReq) (resp tcliservice.TCloseOperationResp, err error) {
+	err = c.invoke("CloseOperation", &req, &resp, func() (err error) {
+		resp, err = c.client.CloseOperation(req)
+		return err
+	})
+	return resp, err
+}
+
+func (c *interceptedClient) GetResultSetMetadata(req tcliservice.TGetResultSetMetadataReq) (resp tcliservice.TGetResultSetMetadataResp, err error) {
+	err = c.invoke("GetResultSetMetadata", &req, &resp, func() (err error) {
+		resp, err = c.client.GetResultSetMetadata(req)
+		return err
+	})
+	return resp, err
+}
+
+func (c *interceptedClient) FetchResults(req tcliservice.TFetchResultsReq) (resp tcliservice.TFetchResultsResp, err error) {
+	err = c.invoke("FetchResults", &req, &resp, func() (err error) {
+		resp, err = c.client.FetchResults(req)
+		return err
+	})
+	return resp, err
+}
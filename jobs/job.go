@@ -0,0 +1,83 @@
+// Package jobs tracks hive queries durably, so that a process can resume
+// monitoring, and reading the results of, queries it submitted before a
+// restart. A Tracker records each query it submits in a Store, with the
+// operation's handle and its state as it changes, and reattaches to the
+// queries still running when it is resumed.
+package jobs
+
+import (
+	"time"
+)
+
+// The state of a job whose operation could no longer be polled, as when it
+// has been closed or the server has restarted.
+const Lost = "LOST"
+
+// A query submitted through a Tracker.
+type Job struct {
+	ID string
+	// The query text, before any arguments were bound to it.
+	Query  string
+	Config map[string]string `json:",omitempty"`
+	// The serialized operation handle, as returned by RowSet.Handle.
+	Handle []byte
+	// The address of the server the query was submitted to, if known.
+	Host string `json:",omitempty"`
+	// The name of the operation's state, such as "RUNNING_STATE", or Lost.
+	State string
+	// Why the job failed or was lost.
+	Error       string `json:",omitempty"`
+	SubmittedAt time.Time
+	UpdatedAt   time.Time
+	// The zero time until the job is complete.
+	CompletedAt time.Time
+	History     []Transition `json:",omitempty"`
+}
+
+// A change in a job's state.
+type Transition struct {
+	State string
+	At    time.Time
+}
+
+// Returns true if the job's operation has finished, failed, been
+// cancelled or closed, or been lost.
+func (j *Job) Complete() bool {
+	switch j.State {
+	case "FINISHED_STATE", "ERROR_STATE", "CANCELED_STATE", "CLOSED_STATE", Lost:
+		return true
+	}
+	return false
+}
+
+// Move the job to a new state, if it differs from the current one.
+// Returns true if it did.
+func (j *Job) transition(state string, at time.Time) bool {
+	if state == j.State {
+		return false
+	}
+
+	j.State = state
+	j.UpdatedAt = at
+	j.History = append(j.History, Transition{state, at})
+	if j.Complete() {
+		j.CompletedAt = at
+	}
+
+	return true
+}
+
+// Returns a copy of the job that shares nothing mutable with it.
+func (j *Job) clone() *Job {
+	c := *j
+	c.Handle = append([]byte(nil), j.Handle...)
+	c.History = append([]Transition(nil), j.History...)
+	if j.Config != nil {
+		c.Config = make(map[string]string, len(j.Config))
+		for k, v := range j.Config {
+			c.Config[k] = v
+		}
+	}
+
+	return &c
+}
@@ -0,0 +1,210 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Returned by Store.Get for a job that isn't stored.
+var ErrNotFound = errors.New("Job not found")
+
+// Stores jobs by ID. Implementations must be safe for concurrent use, and
+// must not retain the jobs passed to Put or share the jobs they return.
+type Store interface {
+	// Store a job, replacing any with the same ID.
+	Put(job *Job) error
+	// Returns the job with the given ID, or ErrNotFound.
+	Get(id string) (*Job, error)
+	// Returns all stored jobs, in order of submission.
+	List() ([]*Job, error)
+	// Remove a job. Removing a job that isn't stored is not an error.
+	Delete(id string) error
+}
+
+// A Store that keeps jobs in memory, for tests and for processes that
+// don't need to survive a restart.
+type MemoryStore struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{jobs: make(map[string]*Job)}
+}
+
+func (s *MemoryStore) Put(job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = job.clone()
+	return nil
+}
+
+func (s *MemoryStore) Get(id string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return job.clone(), nil
+}
+
+func (s *MemoryStore) List() ([]*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job.clone())
+	}
+	sortJobs(jobs)
+	return jobs, nil
+}
+
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, id)
+	return nil
+}
+
+// A Store that keeps each job as a JSON file in a directory. Files are
+// replaced atomically, so a crash mid-write leaves the previous version.
+type FileStore struct {
+	dir string
+	// Serializes writes, so concurrent Puts of a job can't reorder.
+	mu sync.Mutex
+}
+
+// Returns a FileStore keeping jobs in dir, creating it if necessary.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("Error creating job store: %v", err)
+	}
+
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || id[0] == '.' {
+		return "", fmt.Errorf("Invalid job ID %q", id)
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+func (s *FileStore) Put(job *Job) error {
+	path, err := s.path(job.ID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmp, err := ioutil.TempFile(s.dir, ".job-")
+	if err != nil {
+		return fmt.Errorf("Error storing job %s: %v", job.ID, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Error storing job %s: %v", job.ID, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Error storing job %s: %v", job.ID, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Error storing job %s: %v", job.ID, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("Error storing job %s: %v", job.ID, err)
+	}
+
+	return nil
+}
+
+func (s *FileStore) Get(id string) (*Job, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return readJob(path)
+}
+
+func readJob(path string) (*Job, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("Error reading job from %s: %v", path, err)
+	}
+
+	return &job, nil
+}
+
+func (s *FileStore) List() ([]*Job, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*Job, 0, len(paths))
+	for _, path := range paths {
+		job, err := readJob(path)
+		if err == ErrNotFound {
+			// Deleted since the directory was listed.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	sortJobs(jobs)
+	return jobs, nil
+}
+
+func (s *FileStore) Delete(id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func sortJobs(jobs []*Job) {
+	sort.SliceStable(jobs, func(i, j int) bool {
+		if !jobs[i].SubmittedAt.Equal(jobs[j].SubmittedAt) {
+			return jobs[i].SubmittedAt.Before(jobs[j].SubmittedAt)
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+}
@@ -0,0 +1,69 @@
+package jobs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testStore(t *testing.T, store Store) {
+	at := time.Date(2014, 6, 1, 12, 0, 0, 0, time.UTC)
+	first := &Job{ID: "b", Query: "select 1", Handle: []byte{1, 2}, SubmittedAt: at, Config: map[string]string{"k": "v"}}
+	first.transition("INITIALIZED_STATE", at)
+	second := &Job{ID: "a", Query: "select 2", SubmittedAt: at.Add(time.Second)}
+
+	for _, job := range []*Job{first, second} {
+		if err := store.Put(job); err != nil {
+			t.Fatalf("Put error: %v", err)
+		}
+	}
+
+	// Changes after Put aren't stored.
+	first.transition("RUNNING_STATE", at)
+
+	got, err := store.Get("b")
+	if err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	if got.State != "INITIALIZED_STATE" || len(got.History) != 1 || !reflect.DeepEqual(got.Handle, []byte{1, 2}) || got.Config["k"] != "v" {
+		t.Errorf("Unexpected stored job %+v", got)
+	}
+	if !got.SubmittedAt.Equal(at) {
+		t.Errorf("Expected SubmittedAt %v but got %v", at, got.SubmittedAt)
+	}
+
+	jobs, err := store.List()
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].ID != "b" || jobs[1].ID != "a" {
+		t.Errorf("Expected jobs b, a in order of submission but got %v", jobs)
+	}
+
+	if err := store.Delete("b"); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if _, err := store.Get("b"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound for a deleted job but got %v", err)
+	}
+	if err := store.Delete("b"); err != nil {
+		t.Errorf("Expected deleting a missing job to succeed but got %v", err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore error: %v", err)
+	}
+
+	testStore(t, store)
+
+	if err := store.Put(&Job{ID: "../escape"}); err == nil {
+		t.Error("Expected a job ID naming another directory to be rejected")
+	}
+}
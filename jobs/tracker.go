@@ -0,0 +1,302 @@
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/derekgr/hivething"
+)
+
+// The number of consecutive failed polls after which a job is Lost, if
+// Options.MaxPollErrors is unset.
+const DefaultMaxPollErrors = 3
+
+// Options for a Tracker.
+type Options struct {
+	// Controls how often running jobs are polled. If nil,
+	// hivething.DefaultPollStrategy is used.
+	PollStrategy hivething.PollStrategy
+	// The number of consecutive failed polls after which a job is
+	// considered Lost. Defaults to DefaultMaxPollErrors.
+	MaxPollErrors int
+	// Recorded as the Host of each job submitted.
+	Host string
+	// If set, called with a copy of a job each time its state changes,
+	// from the goroutine monitoring it.
+	OnChange func(Job)
+	// If set, failures to store a job's new state are logged to it as
+	// warnings.
+	Logger hivething.Logger
+}
+
+// Submits queries on a client, recording them in a Store, and monitors
+// them in the background until they complete.
+type Tracker struct {
+	client  hivething.Client
+	store   Store
+	options Options
+
+	mu       sync.Mutex
+	monitors map[string]chan struct{}
+	stop     chan struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+var errClosed = errors.New("Tracker is closed")
+
+func NewTracker(client hivething.Client, store Store, options Options) *Tracker {
+	if options.PollStrategy == nil {
+		options.PollStrategy = hivething.DefaultPollStrategy
+	}
+	if options.MaxPollErrors <= 0 {
+		options.MaxPollErrors = DefaultMaxPollErrors
+	}
+
+	return &Tracker{
+		client:   client,
+		store:    store,
+		options:  options,
+		monitors: make(map[string]chan struct{}),
+		stop:     make(chan struct{}),
+	}
+}
+
+func newID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// Submit a query, as hivething.Client.QueryWithConfig does, record it as a
+// job, and monitor it until it completes. Returns the job as recorded,
+// and the RowSet for reading its results.
+func (t *Tracker) Submit(query string, config map[string]string, args ...interface{}) (*Job, hivething.RowSet, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := t.client.QueryWithConfig(query, config, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	handle, err := rows.Handle()
+	if err != nil {
+		rows.Close()
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	job := &Job{
+		ID:          id,
+		Query:       query,
+		Config:      config,
+		Handle:      handle,
+		Host:        t.options.Host,
+		SubmittedAt: now,
+	}
+	job.transition("INITIALIZED_STATE", now)
+
+	if err := t.store.Put(job); err != nil {
+		rows.Close()
+		return nil, nil, err
+	}
+
+	// Nothing will ever update the job if it can't be monitored, so it's
+	// forgotten rather than left looking like it's still running.
+	if err := t.monitor(job.clone()); err != nil {
+		rows.Close()
+		t.store.Delete(id)
+		return nil, nil, err
+	}
+
+	return job, rows, nil
+}
+
+// Reattach to the stored jobs that aren't complete, and monitor them until
+// they are. Call it once on startup. Returns the jobs resumed.
+//
+// Jobs are reattached through the Tracker's client, but hiveserver2 closes
+// an operation along with the session that started it, so a job only
+// survives a restart while its original session stays open on the server:
+// the process must exit without closing its Connection, and return before
+// the session times out. Jobs whose session has closed come back Lost.
+// hivething.ResumeSession tells whether a job's session is still open.
+func (t *Tracker) Resume() ([]*Job, error) {
+	jobs, err := t.store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var resumed []*Job
+	for _, job := range jobs {
+		if job.Complete() {
+			continue
+		}
+
+		t.mu.Lock()
+		_, monitored := t.monitors[job.ID]
+		t.mu.Unlock()
+		if monitored {
+			continue
+		}
+
+		if err := t.monitor(job.clone()); err != nil {
+			return resumed, err
+		}
+		resumed = append(resumed, job)
+	}
+
+	return resumed, nil
+}
+
+// Start polling a job's operation in the background, recording its state
+// as it changes. The job is owned by the monitor from then on.
+func (t *Tracker) monitor(job *Job) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return errClosed
+	}
+
+	done := make(chan struct{})
+	t.monitors[job.ID] = done
+	t.wg.Add(1)
+
+	go func() {
+		defer t.wg.Done()
+		defer func() {
+			t.mu.Lock()
+			delete(t.monitors, job.ID)
+			t.mu.Unlock()
+			close(done)
+		}()
+
+		// The monitor's RowSet is never closed, since that would close the
+		// operation and discard its results.
+		rows, err := t.client.Reattach(job.Handle)
+		if err != nil {
+			job.Error = err.Error()
+			t.update(job, Lost)
+			return
+		}
+
+		failures := 0
+		for attempt := 0; ; attempt++ {
+			status, err := rows.Poll()
+			if err != nil {
+				failures++
+				if failures >= t.options.MaxPollErrors {
+					job.Error = err.Error()
+					t.update(job, Lost)
+					return
+				}
+				status = &hivething.Status{}
+			} else {
+				failures = 0
+				t.update(job, status.State.String())
+				if job.Complete() {
+					return
+				}
+			}
+
+			select {
+			case <-t.stop:
+				return
+			case <-time.After(t.options.PollStrategy.Next(attempt, status)):
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Record a job's new state, if it changed. A failure to store it is only
+// logged, since the job is stored whole on its next change, and jobs whose
+// completion wasn't stored are polled again on Resume.
+func (t *Tracker) update(job *Job, state string) {
+	if !job.transition(state, time.Now()) {
+		return
+	}
+
+	if err := t.store.Put(job); err != nil && t.options.Logger != nil {
+		t.options.Logger.Warn("Error storing job", "job", job.ID, "state", state, "error", err)
+	}
+	if t.options.OnChange != nil {
+		t.options.OnChange(*job.clone())
+	}
+}
+
+// Returns the job with the given ID, as last stored.
+func (t *Tracker) Get(id string) (*Job, error) {
+	return t.store.Get(id)
+}
+
+// Returns all stored jobs, in order of submission.
+func (t *Tracker) List() ([]*Job, error) {
+	return t.store.List()
+}
+
+// Block until the job is complete, or the Tracker is closed, and return
+// it as last stored.
+func (t *Tracker) Wait(id string) (*Job, error) {
+	t.mu.Lock()
+	done := t.monitors[id]
+	t.mu.Unlock()
+
+	if done != nil {
+		select {
+		case <-done:
+		case <-t.stop:
+		}
+	}
+
+	return t.store.Get(id)
+}
+
+// Returns a RowSet for reading the results of a job, by reattaching to
+// its operation.
+func (t *Tracker) Rows(id string) (hivething.RowSet, error) {
+	job, err := t.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.client.Reattach(job.Handle)
+}
+
+// Request that a job's operation be cancelled. Its monitor records the
+// resulting state.
+func (t *Tracker) Cancel(id string) error {
+	rows, err := t.Rows(id)
+	if err != nil {
+		return err
+	}
+
+	return rows.Cancel()
+}
+
+// Stop monitoring jobs, leaving their operations running so that another
+// Tracker can Resume them, as long as the client's session stays open. It
+// doesn't close the client.
+func (t *Tracker) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	close(t.stop)
+	t.mu.Unlock()
+
+	t.wg.Wait()
+	return nil
+}
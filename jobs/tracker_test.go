@@ -0,0 +1,280 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/derekgr/hivething"
+	"github.com/derekgr/hivething/TCLIService"
+	"github.com/derekgr/hivething/hivetest"
+)
+
+var testOptions = hivething.Options{BatchSize: 10, PollStrategy: hivething.FixedInterval(time.Millisecond)}
+
+func connect(t *testing.T, server *hivetest.Server) *hivething.Connection {
+	db, err := hivething.Connect(server.Addr(), testOptions)
+	if err != nil {
+		t.Fatalf("Connect error: %v", err)
+	}
+	return db
+}
+
+func states(job *Job) []string {
+	var states []string
+	for _, transition := range job.History {
+		states = append(states, transition.State)
+	}
+	return states
+}
+
+func TestTrackerSubmit(t *testing.T) {
+	server, err := hivetest.NewServer()
+	if err != nil {
+		t.Fatalf("Can't start fake server: %v", err)
+	}
+	defer server.Close()
+
+	server.Handle("select * from foo").
+		Column("foo.id", tcliservice.TTypeId_INT_TYPE).
+		Row(int32(1)).
+		RunFor(3)
+
+	db := connect(t, server)
+	defer db.Close()
+
+	var (
+		mu      sync.Mutex
+		changes []string
+	)
+	tracker := NewTracker(db, NewMemoryStore(), Options{
+		PollStrategy: hivething.FixedInterval(time.Millisecond),
+		Host:         server.Addr(),
+		OnChange: func(job Job) {
+			mu.Lock()
+			changes = append(changes, job.State)
+			mu.Unlock()
+		},
+	})
+	defer tracker.Close()
+
+	job, rows, err := tracker.Submit("select * from foo", map[string]string{"mapred.job.queue.name": "etl"})
+	if err != nil {
+		t.Fatalf("Submit error: %v", err)
+	}
+	defer rows.Close()
+
+	job, err = tracker.Wait(job.ID)
+	if err != nil {
+		t.Fatalf("Wait error: %v", err)
+	}
+
+	expected := []string{"INITIALIZED_STATE", "RUNNING_STATE", "FINISHED_STATE"}
+	if got := states(job); strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected states %v but got %v", expected, got)
+	}
+	if !job.Complete() || job.CompletedAt.IsZero() || job.Host != server.Addr() || job.Config["mapred.job.queue.name"] != "etl" {
+		t.Errorf("Unexpected completed job %+v", job)
+	}
+
+	mu.Lock()
+	if strings.Join(changes, ",") != strings.Join(expected[1:], ",") {
+		t.Errorf("Expected OnChange for %v but got %v", expected[1:], changes)
+	}
+	mu.Unlock()
+
+	results, err := tracker.Rows(job.ID)
+	if err != nil {
+		t.Fatalf("Rows error: %v", err)
+	}
+	var id int32
+	if !results.Next() || results.Scan(&id) != nil || id != 1 {
+		t.Errorf("Expected to read the job's results through Rows")
+	}
+}
+
+func TestTrackerResume(t *testing.T) {
+	server, err := hivetest.NewServer()
+	if err != nil {
+		t.Fatalf("Can't start fake server: %v", err)
+	}
+	defer server.Close()
+
+	server.Handle("select * from slow").RunFor(1000000)
+	server.Handle("select * from quick").RunFor(1)
+
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore error: %v", err)
+	}
+
+	// Submit jobs, and stop tracking them before the slow one completes,
+	// as if the process had exited.
+	db := connect(t, server)
+	tracker := NewTracker(db, store, Options{PollStrategy: hivething.FixedInterval(time.Millisecond)})
+
+	slow, _, err := tracker.Submit("select * from slow", nil)
+	if err != nil {
+		t.Fatalf("Submit error: %v", err)
+	}
+	quick, _, err := tracker.Submit("select * from quick", nil)
+	if err != nil {
+		t.Fatalf("Submit error: %v", err)
+	}
+	if _, err := tracker.Wait(quick.ID); err != nil {
+		t.Fatalf("Wait error: %v", err)
+	}
+	tracker.Close()
+
+	// A new tracker, with a new connection, picks up the slow job only. The
+	// first connection's session is left open, as it must be for the job's
+	// operation to survive.
+	db2 := connect(t, server)
+	defer db2.Close()
+	tracker = NewTracker(db2, store, Options{PollStrategy: hivething.FixedInterval(time.Millisecond)})
+	defer tracker.Close()
+
+	resumed, err := tracker.Resume()
+	if err != nil {
+		t.Fatalf("Resume error: %v", err)
+	}
+	if len(resumed) != 1 || resumed[0].ID != slow.ID {
+		t.Fatalf("Expected to resume the slow job only but resumed %v", resumed)
+	}
+
+	if err := tracker.Cancel(slow.ID); err != nil {
+		t.Fatalf("Cancel error: %v", err)
+	}
+
+	job, err := tracker.Wait(slow.ID)
+	if err != nil {
+		t.Fatalf("Wait error: %v", err)
+	}
+	if job.State != "CANCELED_STATE" {
+		t.Errorf("Expected the resumed job to be cancelled but it's %s", job.State)
+	}
+
+	db.Close()
+}
+
+func TestTrackerLost(t *testing.T) {
+	server, err := hivetest.NewServer()
+	if err != nil {
+		t.Fatalf("Can't start fake server: %v", err)
+	}
+	defer server.Close()
+
+	db := connect(t, server)
+	defer db.Close()
+
+	store := NewMemoryStore()
+	tracker := NewTracker(db, store, Options{PollStrategy: hivething.FixedInterval(time.Millisecond)})
+	defer tracker.Close()
+
+	// A running job whose operation the server no longer has.
+	server.Handle("select 1").RunFor(1000000)
+	rows, err := db.Query("select 1")
+	if err != nil {
+		t.Fatalf("Query error: %v", err)
+	}
+	handle, _ := rows.Handle()
+	rows.Close()
+
+	store.Put(&Job{ID: "gone", Query: "select 1", Handle: handle, State: "RUNNING_STATE", SubmittedAt: time.Now()})
+	if _, err := tracker.Resume(); err != nil {
+		t.Fatalf("Resume error: %v", err)
+	}
+
+	job, err := tracker.Wait("gone")
+	if err != nil {
+		t.Fatalf("Wait error: %v", err)
+	}
+	if job.State != Lost || job.Error == "" {
+		t.Errorf("Expected the job to be lost with an error, but got %s %q", job.State, job.Error)
+	}
+}
+
+func TestTrackerSubmitClosed(t *testing.T) {
+	server, err := hivetest.NewServer()
+	if err != nil {
+		t.Fatalf("Can't start fake server: %v", err)
+	}
+	defer server.Close()
+
+	db := connect(t, server)
+	defer db.Close()
+
+	store := NewMemoryStore()
+	tracker := NewTracker(db, store, Options{PollStrategy: hivething.FixedInterval(time.Millisecond)})
+	tracker.Close()
+
+	server.Handle("select 1").RunFor(1000000)
+	if _, _, err := tracker.Submit("select 1", nil); err == nil {
+		t.Fatalf("Expected Submit to a closed tracker to fail")
+	}
+
+	if jobs, _ := store.List(); len(jobs) != 0 {
+		t.Errorf("Expected the unmonitored job to be forgotten, but the store has %v", jobs)
+	}
+	if open := server.OpenOperations(); open != 0 {
+		t.Errorf("Expected the job's operation to be closed, but %d are open", open)
+	}
+}
+
+// A Store whose Puts fail once the job has been submitted.
+type failingStore struct {
+	Store
+}
+
+func (s failingStore) Put(job *Job) error {
+	if job.State != "INITIALIZED_STATE" {
+		return errors.New("disk full")
+	}
+	return s.Store.Put(job)
+}
+
+type recordingLogger struct {
+	mu       sync.Mutex
+	warnings []string
+}
+
+func (l *recordingLogger) Debug(msg string, args ...interface{}) {}
+func (l *recordingLogger) Info(msg string, args ...interface{})  {}
+func (l *recordingLogger) Error(msg string, args ...interface{}) {}
+
+func (l *recordingLogger) Warn(msg string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.warnings = append(l.warnings, fmt.Sprint(append([]interface{}{msg}, args...)...))
+}
+
+func TestTrackerLogsStoreFailures(t *testing.T) {
+	server, err := hivetest.NewServer()
+	if err != nil {
+		t.Fatalf("Can't start fake server: %v", err)
+	}
+	defer server.Close()
+
+	server.Handle("select 1").RunFor(1)
+	db := connect(t, server)
+	defer db.Close()
+
+	var logger recordingLogger
+	tracker := NewTracker(db, failingStore{NewMemoryStore()}, Options{PollStrategy: hivething.FixedInterval(time.Millisecond), Logger: &logger})
+	defer tracker.Close()
+
+	job, _, err := tracker.Submit("select 1", nil)
+	if err != nil {
+		t.Fatalf("Submit error: %v", err)
+	}
+	tracker.Wait(job.ID)
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	if len(logger.warnings) == 0 || !strings.Contains(logger.warnings[0], "disk full") {
+		t.Errorf("Expected the failure to store the job's state to be logged but got %q", logger.warnings)
+	}
+}
@@ -0,0 +1,10 @@
+package hivething
+
+// Receives log messages, as alternating keys and values after the
+// message. It is satisfied by *slog.Logger.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
@@ -0,0 +1,95 @@
+package hivething
+
+import (
+	"fmt"
+
+	"github.com/derekgr/hivething/TCLIService"
+)
+
+// The metadata calls take JDBC style patterns, where % matches any run of
+// characters and _ matches any single character. An empty pattern matches
+// everything. Each returns a RowSet for the operation, with the columns
+// documented for the corresponding java.sql.DatabaseMetaData method.
+
+// Lists databases, as DatabaseMetaData.getSchemas.
+func (c *Connection) GetSchemas(schemaPattern string) (RowSet, error) {
+	req := tcliservice.NewTGetSchemasReq()
+	req.SessionHandle = *c.session
+	req.SchemaName = pattern(schemaPattern)
+
+	var resp tcliservice.TGetSchemasResp
+	err := c.options.Retry.call("GetSchemas", func() (status tcliservice.TStatus, err error) {
+		resp, err = c.thrift.GetSchemas(*req)
+		return resp.Status, err
+	})
+	return c.metadataRowSet("GetSchemas", resp.OperationHandle, err)
+}
+
+// Lists tables, as DatabaseMetaData.getTables.
+func (c *Connection) GetTables(schemaPattern, tablePattern string) (RowSet, error) {
+	req := tcliservice.NewTGetTablesReq()
+	req.SessionHandle = *c.session
+	req.SchemaName = pattern(schemaPattern)
+	req.TableName = pattern(tablePattern)
+
+	var resp tcliservice.TGetTablesResp
+	err := c.options.Retry.call("GetTables", func() (status tcliservice.TStatus, err error) {
+		resp, err = c.thrift.GetTables(*req)
+		return resp.Status, err
+	})
+	return c.metadataRowSet("GetTables", resp.OperationHandle, err)
+}
+
+// Lists table columns, as DatabaseMetaData.getColumns.
+func (c *Connection) GetColumns(schemaPattern, tablePattern, columnPattern string) (RowSet, error) {
+	req := tcliservice.NewTGetColumnsReq()
+	req.SessionHandle = *c.session
+	req.SchemaName = pattern(schemaPattern)
+	req.TableName = pattern(tablePattern)
+	req.ColumnName = pattern(columnPattern)
+
+	var resp tcliservice.TGetColumnsResp
+	err := c.options.Retry.call("GetColumns", func() (status tcliservice.TStatus, err error) {
+		resp, err = c.thrift.GetColumns(*req)
+		return resp.Status, err
+	})
+	return c.metadataRowSet("GetColumns", resp.OperationHandle, err)
+}
+
+// Lists functions, as DatabaseMetaData.getFunctions.
+func (c *Connection) GetFunctions(schemaPattern, functionPattern string) (RowSet, error) {
+	req := tcliservice.NewTGetFunctionsReq()
+	req.SessionHandle = *c.session
+	req.SchemaName = pattern(schemaPattern)
+	if functionPattern == "" {
+		functionPattern = "%"
+	}
+	req.FunctionName = tcliservice.TPatternOrIdentifier(functionPattern)
+
+	var resp tcliservice.TGetFunctionsResp
+	err := c.options.Retry.call("GetFunctions", func() (status tcliservice.TStatus, err error) {
+		resp, err = c.thrift.GetFunctions(*req)
+		return resp.Status, err
+	})
+	return c.metadataRowSet("GetFunctions", resp.OperationHandle, err)
+}
+
+func pattern(p string) *tcliservice.TPatternOrIdentifier {
+	if p == "" {
+		return nil
+	}
+
+	val := tcliservice.TPatternOrIdentifier(p)
+	return &val
+}
+
+func (c *Connection) metadataRowSet(call string, operation *tcliservice.TOperationHandle, err error) (RowSet, error) {
+	if _, ok := err.(*ServerError); ok {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error in %s: %v", call, err)
+	}
+
+	return newRowSet(c.thrift, operation, c.options, c.source("")), nil
+}
@@ -0,0 +1,27 @@
+package hivething
+
+import (
+	"time"
+)
+
+// Receives measurements of a Connection's calls to the server, the results
+// it fetches, and the queries it runs, for export to a monitoring system;
+// see the prommetrics package for Prometheus. Methods are called as things
+// happen, including from goroutines prefetching results, so they must be
+// quick and safe for concurrent use.
+type Metrics interface {
+	// After each call to the server, with its method, such as
+	// "FetchResults", how long it took, and the error it failed with, which
+	// is a *ServerError if the server's response reported one.
+	RPC(method string, duration time.Duration, err error)
+	// After each batch of results is fetched, with the number of rows and
+	// their estimated size in memory.
+	Fetched(rows int, bytes int64)
+	// When the server has accepted a query.
+	QueryStarted()
+	// When a poll first finds a query complete, with the state it finished
+	// in and the time since it was submitted. Only queries reported to
+	// QueryStarted are, and not those closed or abandoned before then, or
+	// polled through a RowSet from Reattach.
+	QueryCompleted(state OperationState, duration time.Duration)
+}
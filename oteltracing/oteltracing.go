@@ -0,0 +1,67 @@
+// Package oteltracing traces hivething queries with OpenTelemetry. Queries
+// issued with Connection.QueryContext get a "hivething.Query" span, a child
+// of any span in the context, recording the query's text and operation ID,
+// and the calls made to run it and fetch its results get child spans of
+// their own.
+//
+//	options.Tracer = oteltracing.New(nil)
+//	rows, err := db.QueryContext(ctx, "SELECT ...")
+package oteltracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/derekgr/hivething"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The name of the instrumentation library, given to the TracerProvider.
+const InstrumentationName = "github.com/derekgr/hivething"
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+// Returns a hivething.Tracer starting spans from provider, or from the
+// global TracerProvider if provider is nil.
+func New(provider trace.TracerProvider) hivething.Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+
+	return &tracer{tracer: provider.Tracer(InstrumentationName)}
+}
+
+func (t *tracer) Start(ctx context.Context, name string) (context.Context, hivething.Span) {
+	ctx, s := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+	return ctx, &span{span: s}
+}
+
+type span struct {
+	span trace.Span
+}
+
+func (s *span) SetAttribute(key string, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		s.span.SetAttributes(attribute.String(key, v))
+	case int:
+		s.span.SetAttributes(attribute.Int(key, v))
+	case int64:
+		s.span.SetAttributes(attribute.Int64(key, v))
+	default:
+		s.span.SetAttributes(attribute.String(key, fmt.Sprint(v)))
+	}
+}
+
+func (s *span) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
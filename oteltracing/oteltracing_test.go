@@ -0,0 +1,87 @@
+package oteltracing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/derekgr/hivething"
+	"github.com/derekgr/hivething/TCLIService"
+	"github.com/derekgr/hivething/hivetest"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracer(t *testing.T) {
+	server, err := hivetest.NewServer()
+	if err != nil {
+		t.Fatalf("Can't start fake server: %v", err)
+	}
+	defer server.Close()
+
+	server.Handle("select * from foo").
+		Column("foo.val", tcliservice.TTypeId_STRING_TYPE).
+		Row("foo")
+	server.Handle("select * from bar").Fail()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	options := hivething.Options{BatchSize: 10, PollStrategy: hivething.FixedInterval(time.Millisecond), Tracer: New(provider)}
+	db, err := hivething.Connect(server.Addr(), options)
+	if err != nil {
+		t.Fatalf("Connect error: %v", err)
+	}
+	defer db.Close()
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "caller")
+	rows, err := db.QueryContext(ctx, "select * from foo")
+	if err != nil {
+		t.Fatalf("Connection.QueryContext error: %v", err)
+	}
+	for rows.Next() {
+	}
+	if rows, err = db.QueryContext(ctx, "select * from bar"); err != nil {
+		t.Fatalf("Connection.QueryContext error: %v", err)
+	}
+	rows.Wait()
+	parent.End()
+
+	var queries []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "hivething.Query" {
+			queries = append(queries, span)
+		}
+	}
+	if len(queries) != 2 {
+		t.Fatalf("Expected 2 query spans but got %d", len(queries))
+	}
+
+	for _, query := range queries {
+		if query.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("Expected the query's span to be a child of the caller's")
+		}
+	}
+
+	attributes := map[string]string{}
+	for _, attribute := range queries[0].Attributes() {
+		attributes[string(attribute.Key)] = attribute.Value.Emit()
+	}
+	if attributes["db.statement"] != "select * from foo" || attributes["hive.operation_id"] == "" {
+		t.Errorf("Expected the query's text and operation ID but got %v", attributes)
+	}
+	if queries[0].Status().Code == codes.Error || queries[1].Status().Code != codes.Error {
+		t.Errorf("Expected only the failed query's span to have an error status, but got %v and %v", queries[0].Status(), queries[1].Status())
+	}
+
+	children := 0
+	for _, span := range recorder.Ended() {
+		if span.Parent().SpanID() == queries[0].SpanContext().SpanID() {
+			children++
+		}
+	}
+	if children != 4 {
+		t.Errorf("Expected ExecuteStatement, poll, metadata and fetch spans but got %d children", children)
+	}
+}
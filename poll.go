@@ -0,0 +1,125 @@
+package hivething
+
+import (
+	"math/rand"
+	"time"
+)
+
+// A PollStrategy decides how long Wait sleeps between operation status
+// checks. Next is called after every unfinished Poll with the number of
+// polls already made (starting at 0) and the status just observed.
+type PollStrategy interface {
+	Next(attempt int, status *Status) time.Duration
+}
+
+// FixedInterval polls at a constant interval.
+type FixedInterval time.Duration
+
+func (f FixedInterval) Next(attempt int, status *Status) time.Duration {
+	return time.Duration(f)
+}
+
+// ExponentialBackoff starts polling at Initial and multiplies the delay by
+// Multiplier after every poll, never waiting longer than Max. Jitter is the
+// fraction (0 to 1) of each delay that is randomized, so that many clients
+// waiting on the same server don't poll in lockstep. If Initial isn't
+// positive, polling starts at DefaultPollStrategy's initial 100ms, so that
+// the zero value doesn't poll the server continuously. If Max isn't
+// positive, delays stop growing at a minute, or Initial if that's longer.
+type ExponentialBackoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	Jitter     float64
+}
+
+func (e ExponentialBackoff) Next(attempt int, status *Status) time.Duration {
+	multiplier := e.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	initial := e.Initial
+	if initial <= 0 {
+		initial = defaultPollInitial
+	}
+
+	limit := e.Max
+	if limit <= 0 {
+		limit = defaultBackoffMax
+		if initial > limit {
+			limit = initial
+		}
+	}
+
+	delay := float64(initial)
+	for i := 0; i < attempt && delay < float64(limit); i++ {
+		delay *= multiplier
+	}
+
+	if delay > float64(limit) {
+		delay = float64(limit)
+	}
+
+	if e.Jitter > 0 {
+		jitter := e.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		delay -= delay * jitter * rand.Float64()
+	}
+
+	return time.Duration(delay)
+}
+
+// AdaptiveBackoff polls at the Queued interval while the operation is still
+// waiting to be scheduled, which is usually brief, and defers to Running once
+// the server reports it has started work, counting attempts for Running from
+// the first poll that found the operation started. A Queued interval, or
+// one from Running, that isn't positive is taken as 100ms, as
+// ExponentialBackoff's zero Initial is.
+type AdaptiveBackoff struct {
+	Queued  time.Duration
+	Running PollStrategy
+}
+
+func (a AdaptiveBackoff) Next(attempt int, status *Status) time.Duration {
+	delay := a.Queued
+	if !status.isQueued() && a.Running != nil {
+		attempt -= status.queuedPolls
+		if attempt < 0 {
+			attempt = 0
+		}
+		delay = a.Running.Next(attempt, status)
+	}
+
+	if delay <= 0 {
+		return defaultPollInitial
+	}
+	return delay
+}
+
+const (
+	defaultPollInitial = 100 * time.Millisecond
+	// ExponentialBackoff's cap when Max isn't set.
+	defaultBackoffMax = time.Minute
+)
+
+var (
+	// Used when Options specifies neither a PollStrategy nor a PollIntervalSeconds.
+	DefaultPollStrategy PollStrategy = ExponentialBackoff{
+		Initial:    defaultPollInitial,
+		Max:        5 * time.Second,
+		Multiplier: 1.5,
+		Jitter:     0.2,
+	}
+)
+
+func (s Status) isQueued() bool {
+	switch s.State {
+	case StateUnknown, StateInitialized, StatePending:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,101 @@
+package hivething
+
+import (
+	"testing"
+	"time"
+)
+
+func statusIn(state OperationState) *Status {
+	return &Status{State: state}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff{Initial: time.Second, Max: 5 * time.Second, Multiplier: 2}
+	running := statusIn(StateRunning)
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for attempt, want := range expected {
+		if got := backoff.Next(attempt, running); got != want {
+			t.Errorf("Attempt %d: expected %v but got %v", attempt, want, got)
+		}
+	}
+}
+
+func TestExponentialBackoffZeroValue(t *testing.T) {
+	var backoff ExponentialBackoff
+	for attempt := 0; attempt < 3; attempt++ {
+		if got := backoff.Next(attempt, statusIn(StateRunning)); got != defaultPollInitial {
+			t.Errorf("Attempt %d: expected the zero value to poll every %v but got %v", attempt, defaultPollInitial, got)
+		}
+	}
+}
+
+func TestExponentialBackoffUncapped(t *testing.T) {
+	backoff := ExponentialBackoff{Initial: time.Second, Multiplier: 2}
+	running := statusIn(StateRunning)
+
+	for _, attempt := range []int{6, 34, 100, 10000} {
+		if got := backoff.Next(attempt, running); got != defaultBackoffMax {
+			t.Errorf("Attempt %d: expected delays without a Max to stop at %v but got %v", attempt, defaultBackoffMax, got)
+		}
+	}
+
+	backoff.Initial = 2 * time.Minute
+	if got := backoff.Next(100, running); got != 2*time.Minute {
+		t.Errorf("Expected an Initial over the default cap to be kept but got %v", got)
+	}
+}
+
+func TestExponentialBackoffJitter(t *testing.T) {
+	backoff := ExponentialBackoff{Initial: time.Second, Max: time.Second, Multiplier: 2, Jitter: 0.5}
+	running := statusIn(StateRunning)
+
+	for i := 0; i < 100; i++ {
+		if got := backoff.Next(i, running); got < 500*time.Millisecond || got > time.Second {
+			t.Fatalf("Expected jittered delay in [500ms, 1s] but got %v", got)
+		}
+	}
+}
+
+func TestAdaptiveBackoff(t *testing.T) {
+	adaptive := AdaptiveBackoff{Queued: 10 * time.Millisecond, Running: FixedInterval(time.Second)}
+
+	if got := adaptive.Next(3, statusIn(StatePending)); got != 10*time.Millisecond {
+		t.Errorf("Expected queued interval while pending but got %v", got)
+	}
+
+	if got := adaptive.Next(3, statusIn(StateRunning)); got != time.Second {
+		t.Errorf("Expected running interval while running but got %v", got)
+	}
+
+	// After 10 polls in the queue, running backoff starts from its first attempt.
+	adaptive.Running = ExponentialBackoff{Initial: time.Second, Max: time.Minute, Multiplier: 2}
+	running := statusIn(StateRunning)
+	running.queuedPolls = 10
+	if got := adaptive.Next(10, running); got != time.Second {
+		t.Errorf("Expected the first running interval after queueing but got %v", got)
+	}
+	if got := adaptive.Next(12, running); got != 4*time.Second {
+		t.Errorf("Expected the third running interval but got %v", got)
+	}
+
+	var zero AdaptiveBackoff
+	if got := zero.Next(0, statusIn(StatePending)); got != defaultPollInitial {
+		t.Errorf("Expected a zero Queued interval to poll every %v but got %v", defaultPollInitial, got)
+	}
+	zero.Running = FixedInterval(0)
+	if got := zero.Next(0, statusIn(StateRunning)); got != defaultPollInitial {
+		t.Errorf("Expected a zero Running interval to poll every %v but got %v", defaultPollInitial, got)
+	}
+}
+
+func TestOptionsPollStrategy(t *testing.T) {
+	if s := DefaultOptions.pollStrategy(); s != DefaultPollStrategy {
+		t.Errorf("Expected DefaultPollStrategy but got %v", s)
+	}
+
+	legacy := Options{PollIntervalSeconds: 2}
+	if got := legacy.pollStrategy().Next(0, nil); got != 2*time.Second {
+		t.Errorf("Expected PollIntervalSeconds to be honored but got %v", got)
+	}
+}
@@ -0,0 +1,117 @@
+package hivething
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/derekgr/hivething/TCLIService"
+)
+
+// How far an operation has got, as of a Poll.
+//
+// Hive 2.3 and later report the percentage done and the progress of each
+// stage, such as a Tez vertex or Spark stage, when asked as they're polled.
+// Older servers, and engines that don't measure progress, such as
+// MapReduce, don't, so Percent is then only known once the operation
+// finishes, and otherwise progress is what the states observed so far, and
+// their timing, tell.
+type Progress struct {
+	// Since the operation was submitted, or for one reattached from an
+	// older handle that doesn't record that, since it was reattached.
+	Elapsed time.Duration
+	// The status of the operation when each change of state was first
+	// observed, oldest first, without their own Progress.
+	History []Status
+	// From 0 to 100, or -1 if unknown.
+	Percent float64
+	// Each stage of the query, in the server's order, if it reported them.
+	Stages []StageProgress
+}
+
+// The progress of one stage of a query, as the server reports it.
+type StageProgress struct {
+	// Such as "Map 1" or "Stage-1_0".
+	Name string
+	// Such as "RUNNING" or "SUCCEEDED".
+	Status string
+	// The number of tasks in the stage, and how many of them are in each
+	// state.
+	Total, Completed, Running, Pending, Failed, Killed int
+}
+
+// Record a newly polled status, and set its timing and Progress, from the
+// server's progress update if it sent one. Reports a query this RowSet
+// started to Metrics when it's first seen to complete.
+func (r *rowSet) observe(status *Status, update *tcliservice.TProgressUpdateResp) {
+	if status.IsComplete() && r.completedAt.IsZero() {
+		r.completedAt = status.At
+		if r.options.Metrics != nil && r.started {
+			r.options.Metrics.QueryCompleted(status.State, r.completedAt.Sub(r.submittedAt))
+		}
+	}
+	if !status.isQueued() && r.startedAt.IsZero() {
+		r.startedAt = status.At
+	}
+	status.SubmittedAt, status.StartedAt, status.CompletedAt = r.submittedAt, r.startedAt, r.completedAt
+
+	if n := len(r.history); n == 0 || r.history[n-1].State != status.State {
+		r.history = append(r.history, *status)
+	}
+
+	progress := &Progress{
+		Elapsed: status.At.Sub(r.submittedAt),
+		History: append([]Status(nil), r.history...),
+		Percent: -1,
+	}
+	if update != nil && update.Status != tcliservice.TJobExecutionStatus_NOT_AVAILABLE {
+		// Hive reports a fraction, despite the name.
+		progress.Percent = 100 * update.ProgressedPercentage
+		progress.Stages = stageProgress(update)
+	}
+	if status.IsSuccess() {
+		progress.Percent = 100
+	}
+
+	status.Progress = progress
+}
+
+// Reads the table of stages from a progress update, such as beeline
+// prints, finding the columns by their headers.
+func stageProgress(update *tcliservice.TProgressUpdateResp) []StageProgress {
+	columns := make(map[string]int, len(update.HeaderNames))
+	for i, name := range update.HeaderNames {
+		columns[strings.ToUpper(name)] = i
+	}
+
+	var stages []StageProgress
+	for _, row := range update.Rows {
+		if len(row) == 0 {
+			continue
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		count := func(name string) int {
+			n, _ := strconv.Atoi(field(name))
+			return n
+		}
+
+		stages = append(stages, StageProgress{
+			Name:      strings.TrimSpace(row[0]),
+			Status:    field("STATUS"),
+			Total:     count("TOTAL"),
+			Completed: count("COMPLETED"),
+			Running:   count("RUNNING"),
+			Pending:   count("PENDING"),
+			Failed:    count("FAILED"),
+			Killed:    count("KILLED"),
+		})
+	}
+
+	return stages
+}
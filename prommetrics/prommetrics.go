@@ -0,0 +1,141 @@
+// Package prommetrics reports hivething's Metrics to Prometheus: the
+// latency and errors of calls to hiveserver2, the rows and bytes fetched,
+// and the number and duration of queries.
+//
+//	metrics := prommetrics.New(prommetrics.Options{})
+//	prometheus.MustRegister(metrics)
+//	options.Metrics = metrics
+package prommetrics
+
+import (
+	"time"
+
+	"github.com/derekgr/hivething"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Options for the metrics collected.
+type Options struct {
+	// Prefixes the metrics' names; defaults to "hivething".
+	Namespace string
+	// Labels added to every metric, such as the cluster queried.
+	ConstLabels prometheus.Labels
+	// Histogram buckets, in seconds, for call latency. Defaults to
+	// prometheus.DefBuckets.
+	RPCBuckets []float64
+	// Histogram buckets, in seconds, for query duration. Defaults to
+	// DefaultQueryBuckets.
+	QueryBuckets []float64
+}
+
+var (
+	// Query duration buckets, from a second to two hours.
+	DefaultQueryBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 1800, 3600, 7200}
+)
+
+// Implements hivething.Metrics, and is a prometheus.Collector of the
+// metrics:
+//
+//	hivething_rpc_duration_seconds{method,result}  histogram of calls, by method and "ok" or "error"
+//	hivething_fetched_rows_total                   counter of rows fetched
+//	hivething_fetched_bytes_total                  counter of the estimated in-memory size of rows fetched
+//	hivething_queries_started_total                counter of queries accepted by the server
+//	hivething_query_duration_seconds{state}        histogram of completed queries, by final state
+type Metrics struct {
+	rpcDuration    *prometheus.HistogramVec
+	fetchedRows    prometheus.Counter
+	fetchedBytes   prometheus.Counter
+	queriesStarted prometheus.Counter
+	queryDuration  *prometheus.HistogramVec
+}
+
+// Returns Metrics to register with a prometheus.Registerer and set in
+// hivething.Options. One Metrics can be shared by many Connections.
+func New(options Options) *Metrics {
+	namespace := options.Namespace
+	if namespace == "" {
+		namespace = "hivething"
+	}
+	rpcBuckets := options.RPCBuckets
+	if rpcBuckets == nil {
+		rpcBuckets = prometheus.DefBuckets
+	}
+	queryBuckets := options.QueryBuckets
+	if queryBuckets == nil {
+		queryBuckets = DefaultQueryBuckets
+	}
+
+	return &Metrics{
+		rpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   namespace,
+			Name:        "rpc_duration_seconds",
+			Help:        "Latency of calls to hiveserver2.",
+			ConstLabels: options.ConstLabels,
+			Buckets:     rpcBuckets,
+		}, []string{"method", "result"}),
+		fetchedRows: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "fetched_rows_total",
+			Help:        "Rows fetched from hiveserver2.",
+			ConstLabels: options.ConstLabels,
+		}),
+		fetchedBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "fetched_bytes_total",
+			Help:        "Estimated in-memory size of rows fetched from hiveserver2.",
+			ConstLabels: options.ConstLabels,
+		}),
+		queriesStarted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "queries_started_total",
+			Help:        "Queries accepted by hiveserver2.",
+			ConstLabels: options.ConstLabels,
+		}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   namespace,
+			Name:        "query_duration_seconds",
+			Help:        "Time from submitting a query to seeing it complete.",
+			ConstLabels: options.ConstLabels,
+			Buckets:     queryBuckets,
+		}, []string{"state"}),
+	}
+}
+
+func (m *Metrics) RPC(method string, duration time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	m.rpcDuration.WithLabelValues(method, result).Observe(duration.Seconds())
+}
+
+func (m *Metrics) Fetched(rows int, bytes int64) {
+	m.fetchedRows.Add(float64(rows))
+	m.fetchedBytes.Add(float64(bytes))
+}
+
+func (m *Metrics) QueryStarted() {
+	m.queriesStarted.Inc()
+}
+
+func (m *Metrics) QueryCompleted(state hivething.OperationState, duration time.Duration) {
+	m.queryDuration.WithLabelValues(state.String()).Observe(duration.Seconds())
+}
+
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.rpcDuration.Describe(ch)
+	m.fetchedRows.Describe(ch)
+	m.fetchedBytes.Describe(ch)
+	m.queriesStarted.Describe(ch)
+	m.queryDuration.Describe(ch)
+}
+
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.rpcDuration.Collect(ch)
+	m.fetchedRows.Collect(ch)
+	m.fetchedBytes.Collect(ch)
+	m.queriesStarted.Collect(ch)
+	m.queryDuration.Collect(ch)
+}
+
+var _ hivething.Metrics = (*Metrics)(nil)
@@ -0,0 +1,68 @@
+package prommetrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/derekgr/hivething"
+	"github.com/derekgr/hivething/TCLIService"
+	"github.com/derekgr/hivething/hivetest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	server, err := hivetest.NewServer()
+	if err != nil {
+		t.Fatalf("Can't start fake server: %v", err)
+	}
+	defer server.Close()
+
+	server.Handle("select * from foo").
+		Column("foo.val", tcliservice.TTypeId_STRING_TYPE).
+		Row("foo").Row("bar").Row("baz")
+
+	metrics := New(Options{ConstLabels: prometheus.Labels{"cluster": "test"}})
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(metrics)
+
+	options := hivething.Options{BatchSize: 2, PollStrategy: hivething.FixedInterval(time.Millisecond), Metrics: metrics}
+	db, err := hivething.Connect(server.Addr(), options)
+	if err != nil {
+		t.Fatalf("Connect error: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("select * from foo")
+	if err != nil {
+		t.Fatalf("Connection.Query error: %v", err)
+	}
+	for rows.Next() {
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("Iteration error: %v", err)
+	}
+
+	expected := `
+# HELP hivething_fetched_rows_total Rows fetched from hiveserver2.
+# TYPE hivething_fetched_rows_total counter
+hivething_fetched_rows_total{cluster="test"} 3
+# HELP hivething_queries_started_total Queries accepted by hiveserver2.
+# TYPE hivething_queries_started_total counter
+hivething_queries_started_total{cluster="test"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "hivething_fetched_rows_total", "hivething_queries_started_total"); err != nil {
+		t.Error(err)
+	}
+
+	if n := testutil.CollectAndCount(metrics, "hivething_rpc_duration_seconds"); n != 5 {
+		t.Errorf("Expected latencies for 5 methods but got %d", n)
+	}
+	if n := testutil.ToFloat64(metrics.fetchedBytes); n <= 0 {
+		t.Errorf("Expected the size of fetched rows to be counted but got %v", n)
+	}
+	if n := testutil.CollectAndCount(metrics, "hivething_query_duration_seconds"); n != 1 {
+		t.Errorf("Expected the query's duration to be observed but got %d series", n)
+	}
+}
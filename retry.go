@@ -0,0 +1,115 @@
+package hivething
+
+import (
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+	"github.com/derekgr/hivething/TCLIService"
+)
+
+// Controls how calls that fail transiently are retried: Connect, the
+// metadata calls, Poll, and QueryIdempotent. The zero value makes every
+// call once.
+//
+// A call that fails because the connection's socket has broken would fail
+// again on the same Connection, so network and thrift transport errors are
+// retried only by Connect, which dials afresh each time, whatever
+// Retryable says.
+type RetryPolicy struct {
+	// The most times a call is made, including the first.
+	MaxAttempts int
+	// The delay before each retry, given the number of retries already
+	// made (starting at 0) and an unknown Status. If nil,
+	// DefaultRetryBackoff is used.
+	Backoff PollStrategy
+	// Returns true if a call that failed with an error the server
+	// reported should be retried. If nil, IsTransient is used.
+	Retryable func(err error) bool
+	// If set, called before each retry with the call's name, such as
+	// "GetOperationStatus", the number of attempts made, and the error
+	// the last one failed with.
+	OnRetry func(call string, attempts int, err error)
+}
+
+var (
+	// Used when a RetryPolicy doesn't specify a Backoff.
+	DefaultRetryBackoff PollStrategy = ExponentialBackoff{
+		Initial:    500 * time.Millisecond,
+		Max:        10 * time.Second,
+		Multiplier: 2,
+		Jitter:     0.2,
+	}
+)
+
+// Returns true for errors from the server that may not recur if the call
+// is retried: STILL_EXECUTING_STATUS responses, and hiveserver2 refusing a
+// session because it has too many.
+func IsTransient(err error) bool {
+	if e, ok := err.(*ServerError); ok {
+		if e.StatusCode == tcliservice.TStatusCode_STILL_EXECUTING_STATUS {
+			return true
+		}
+		return strings.Contains(strings.ToLower(e.Message), "too many sessions")
+	}
+
+	return false
+}
+
+// Returns true for network and thrift transport errors, after which a
+// connection can't be used.
+func isTransportError(err error) bool {
+	switch err.(type) {
+	case thrift.TTransportException, net.Error:
+		return true
+	}
+
+	return err == io.EOF || err == io.ErrUnexpectedEOF
+}
+
+// Make a call, retrying it as the policy allows. Transport errors are
+// retried only if redial is set, for calls that open a new connection
+// each attempt. Returns the number of attempts made, and the last one's
+// error.
+func (p RetryPolicy) do(call string, redial bool, f func() error) (int, error) {
+	backoff := p.Backoff
+	if backoff == nil {
+		backoff = DefaultRetryBackoff
+	}
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsTransient
+	}
+
+	for attempts := 1; ; attempts++ {
+		err := f()
+		if err == nil || attempts >= p.MaxAttempts {
+			return attempts, err
+		}
+		if transport := isTransportError(err); transport && !redial || !transport && !retryable(err) {
+			return attempts, err
+		}
+
+		if p.OnRetry != nil {
+			p.OnRetry(call, attempts, err)
+		}
+		time.Sleep(backoff.Next(attempts-1, &Status{}))
+	}
+}
+
+// Make a thrift call, retrying it as the policy allows. The call returns
+// its response's status, which is turned into a *ServerError if it isn't
+// a success, so that it can be classified.
+func (p RetryPolicy) call(call string, f func() (tcliservice.TStatus, error)) error {
+	_, err := p.do(call, false, func() error {
+		status, err := f()
+		if err == nil && !isSuccessStatus(status) {
+			err = newServerError(call, status)
+		}
+		return err
+	})
+
+	return err
+}
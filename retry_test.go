@@ -0,0 +1,79 @@
+package hivething
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/derekgr/hivething/TCLIService"
+)
+
+func TestIsTransient(t *testing.T) {
+	cases := []struct {
+		err       error
+		transient bool
+	}{
+		{io.EOF, false},
+		{&net.OpError{Op: "read", Err: errors.New("connection reset by peer")}, false},
+		{&ServerError{Call: "ExecuteStatement", StatusCode: tcliservice.TStatusCode_STILL_EXECUTING_STATUS}, true},
+		{&ServerError{Call: "OpenSession", StatusCode: tcliservice.TStatusCode_ERROR_STATUS, Message: "Too many sessions open"}, true},
+		{&ServerError{Call: "ExecuteStatement", StatusCode: tcliservice.TStatusCode_ERROR_STATUS, Message: "ParseException"}, false},
+		{errors.New("Query failed execution: ERROR_STATE"), false},
+	}
+
+	for _, c := range cases {
+		if got := IsTransient(c.err); got != c.transient {
+			t.Errorf("Expected IsTransient(%v) to be %v", c.err, c.transient)
+		}
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	var retries []int
+	policy := RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     FixedInterval(time.Millisecond),
+		OnRetry: func(call string, attempts int, err error) {
+			retries = append(retries, attempts)
+		},
+	}
+
+	calls := 0
+	attempts, err := policy.do("Test", true, func() error {
+		calls++
+		return io.EOF
+	})
+	if err != io.EOF || attempts != 3 || calls != 3 || len(retries) != 2 {
+		t.Errorf("Expected 3 failed attempts and 2 retries but got %d attempts, %d calls, retries %v, %v", attempts, calls, retries, err)
+	}
+
+	// A broken connection isn't retried by calls that keep using it, even
+	// if Retryable allows it.
+	calls = 0
+	everything := policy
+	everything.Retryable = func(error) bool { return true }
+	if attempts, _ := everything.do("Test", false, func() error {
+		calls++
+		return &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}
+	}); attempts != 1 || calls != 1 {
+		t.Errorf("Expected a transport error not to be retried without redialing but got %d attempts", attempts)
+	}
+
+	calls = 0
+	if attempts, err := policy.do("Test", false, func() error {
+		calls++
+		return errors.New("permanent")
+	}); err == nil || attempts != 1 || calls != 1 {
+		t.Errorf("Expected a permanent error not to be retried but got %d attempts", attempts)
+	}
+
+	calls = 0
+	if attempts, _ := (RetryPolicy{}).do("Test", true, func() error {
+		calls++
+		return io.EOF
+	}); attempts != 1 || calls != 1 {
+		t.Errorf("Expected the zero RetryPolicy to make one attempt but got %d", attempts)
+	}
+}
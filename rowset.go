@@ -1,9 +1,9 @@
 package hivething
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
 	"time"
 
 	"git.apache.org/thrift.git/lib/go/thrift"
@@ -11,9 +11,11 @@ import (
 )
 
 type rowSet struct {
-	thrift    *tcliservice.TCLIServiceClient
+	thrift    tcliservice.TCLIService
 	operation *tcliservice.TOperationHandle
 	options   Options
+	// Recorded in the operation's Handle.
+	source HandleInfo
 
 	columns    []*tcliservice.TColumnDesc
 	columnStrs []string
@@ -24,6 +26,30 @@ type rowSet struct {
 	ready   bool
 
 	nextRow []interface{}
+	// Why Next last returned false, if it failed.
+	err error
+
+	// Set once prefetching has started.
+	batches chan fetchedBatch
+	stop    chan struct{}
+	closed  bool
+
+	// For the timing and Progress of polled statuses.
+	submittedAt time.Time
+	startedAt   time.Time
+	completedAt time.Time
+	history     []Status
+
+	// Running totals for sizing batches to Options.BatchMemoryBytes.
+	rowsFetched  int64
+	bytesFetched int64
+
+	// Set for a query traced with Options.Tracer.
+	trace *queryTrace
+	// Set for a query this RowSet's Connection started, as reported to
+	// Options.Metrics, so that its completion is reported too. Metadata
+	// calls and reattached operations aren't.
+	started bool
 }
 
 // A RowSet represents an asyncronous hive operation. You can
@@ -33,89 +59,156 @@ type rowSet struct {
 type RowSet interface {
 	Handle() ([]byte, error)
 	Columns() []string
+	ColumnTypes() ([]*ColumnType, error)
 	Next() bool
+	Err() error
 	Scan(dest ...interface{}) error
 	Poll() (*Status, error)
 	Wait() (*Status, error)
+	WaitWithProgress(report func(Progress)) (*Status, error)
+	Stream(ctx context.Context, bufferSize int) (<-chan Row, <-chan error)
+	Cancel() error
+	Close() error
 }
 
 // Represents job status, including success state and time the
 // status was updated.
 type Status struct {
-	state *tcliservice.TOperationState
+	State OperationState
+	// Why the operation failed, as a *ServerError, if it's in StateError
+	// and the server said.
 	Error error
 	At    time.Time
+	// When the operation was submitted, and when it was first seen to be
+	// running and complete, by polls of this RowSet; zero until then. An
+	// operation that starts and finishes between polls is only seen to
+	// complete, so StartedAt is set then too.
+	SubmittedAt time.Time
+	StartedAt   time.Time
+	CompletedAt time.Time
+	// The number of times the query was issued, if by QueryIdempotent.
+	Attempts int
+	// Set by Poll and Wait on a RowSet from a Connection, or a static one.
+	Progress *Progress
+
+	// How many of the polls Wait has made found the operation queued, so
+	// that AdaptiveBackoff can count attempts from when it started.
+	queuedPolls int
 }
 
-func newRowSet(thrift *tcliservice.TCLIServiceClient, operation *tcliservice.TOperationHandle, options Options) RowSet {
-	return &rowSet{thrift, operation, options, nil, nil, 0, nil, true, false, nil}
+func newRowSet(thrift tcliservice.TCLIService, operation *tcliservice.TOperationHandle, options Options, source HandleInfo) *rowSet {
+	submittedAt := source.CreatedAt
+	if submittedAt.IsZero() {
+		submittedAt = time.Now()
+	}
+
+	return &rowSet{thrift: thrift, operation: operation, options: options, source: source, submittedAt: submittedAt, hasMore: true}
 }
 
 // Construct a RowSet for a previously submitted operation, using the prior operation's Handle()
 // and a valid thrift client to a hive service that is aware of the operation.
 func Reattach(conn *Connection, handle []byte) (RowSet, error) {
-	operation, err := deserializeOp(handle)
-	if err != nil {
-		return nil, err
-	}
-
-	return newRowSet(conn.thrift, operation, conn.options), nil
+	return conn.Reattach(handle)
 }
 
 // Issue a thrift call to check for the job's current status.
 func (r *rowSet) Poll() (*Status, error) {
 	req := tcliservice.NewTGetOperationStatusReq()
 	req.OperationHandle = *r.operation
-
-	resp, err := r.thrift.GetOperationStatus(*req)
-	if err != nil {
-		return nil, fmt.Errorf("Error getting status: %+v, %v", resp, err)
+	// Servers before hive 2.3 ignore this.
+	progressUpdate := true
+	req.GetProgressUpdate = &progressUpdate
+
+	span := r.trace.call("GetOperationStatus")
+	var resp tcliservice.TGetOperationStatusResp
+	err := r.options.Retry.call("GetOperationStatus", func() (status tcliservice.TStatus, err error) {
+		resp, err = r.thrift.GetOperationStatus(*req)
+		return resp.Status, err
+	})
+	if err == nil && resp.OperationState != nil {
+		span.SetAttribute("hive.operation_state", newOperationState(resp.OperationState).String())
 	}
-
-	if !isSuccessStatus(resp.Status) {
-		return nil, fmt.Errorf("GetStatus call failed: %s", resp.Status.String())
+	span.End(err)
+	if _, ok := err.(*ServerError); ok {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error getting status: %v", err)
 	}
 
 	if resp.OperationState == nil {
 		return nil, errors.New("No error from GetStatus, but nil status!")
 	}
 
-	return &Status{resp.OperationState, nil, time.Now()}, nil
+	status := &Status{
+		State: newOperationState(resp.OperationState),
+		Error: operationError(resp),
+		At:    time.Now(),
+	}
+	r.observe(status, resp.ProgressUpdateResponse)
+	if status.IsComplete() {
+		r.trace.setAttribute("hive.operation_state", status.State.String())
+	}
+	return status, nil
 }
 
 // Wait until the job is complete, one way or another, returning Status and error.
 func (r *rowSet) Wait() (*Status, error) {
-	for {
+	return r.WaitWithProgress(nil)
+}
+
+// Like Wait, but calls report, if non-nil, with the operation's progress
+// after every poll, including the last.
+func (r *rowSet) WaitWithProgress(report func(Progress)) (*Status, error) {
+	poll := r.options.pollStrategy()
+	queuedPolls := 0
+	for attempt := 0; ; attempt++ {
 		status, err := r.Poll()
 
 		if err != nil {
 			return nil, err
 		}
 
+		if status.isQueued() {
+			queuedPolls++
+		}
+		status.queuedPolls = queuedPolls
+
+		if report != nil {
+			report(*status.Progress)
+		}
+
 		if status.IsComplete() {
 			if status.IsSuccess() {
 				// Fetch operation metadata.
 				metadataReq := tcliservice.NewTGetResultSetMetadataReq()
 				metadataReq.OperationHandle = *r.operation
 
+				span := r.trace.call("GetResultSetMetadata")
 				metadataResp, err := r.thrift.GetResultSetMetadata(*metadataReq)
+				if err == nil && !isSuccessStatus(metadataResp.Status) {
+					err = fmt.Errorf("GetResultSetMetadata failed: %s", metadataResp.Status.String())
+				}
+				span.End(err)
 				if err != nil {
+					r.trace.end(err)
 					return nil, err
 				}
 
-				if !isSuccessStatus(metadataResp.Status) {
-					return nil, fmt.Errorf("GetResultSetMetadata failed: %s", metadataResp.Status.String())
-				}
-
 				r.columns = metadataResp.Schema.Columns
 				r.ready = true
 
 				return status, nil
 			}
-			return nil, fmt.Errorf("Query failed execution: %s", status.state.String())
+			err := fmt.Errorf("Query failed execution: %s", status)
+			if status.Error != nil {
+				err = fmt.Errorf("Query failed execution: %s: %v", status, status.Error)
+			}
+			r.trace.end(err)
+			return status, err
 		}
 
-		time.Sleep(time.Duration(r.options.PollIntervalSeconds) * time.Second)
+		time.Sleep(poll.Next(attempt, status))
 	}
 }
 
@@ -137,43 +230,41 @@ func (r *rowSet) waitForSuccess() error {
 // the operation is successful, blocking until the operation is
 // complete, if necessary.
 // Returns true is a row is available to Scan(), and false if the
-// results are empty or any other error occurs.
+// results are exhausted or an error occurs, which Err then returns.
 func (r *rowSet) Next() bool {
+	if r.err != nil {
+		return false
+	}
+
 	if err := r.waitForSuccess(); err != nil {
+		r.err = err
+		r.trace.end(err)
 		return false
 	}
 
 	if r.rowSet == nil || r.offset >= len(r.rowSet.Rows) {
-		if !r.hasMore {
-			return false
-		}
-
-		fetchReq := tcliservice.NewTFetchResultsReq()
-		fetchReq.OperationHandle = *r.operation
-		fetchReq.Orientation = tcliservice.TFetchOrientation_FETCH_NEXT
-		fetchReq.MaxRows = r.options.BatchSize
-
-		resp, err := r.thrift.FetchResults(*fetchReq)
+		rowSet, err := r.nextBatch()
 		if err != nil {
-			log.Printf("FetchResults failed: %v\n", err)
+			r.err = err
+			r.trace.end(err)
 			return false
 		}
 
-		if !isSuccessStatus(resp.Status) {
-			log.Printf("FetchResults failed: %s\n", resp.Status.String())
+		if rowSet == nil || len(rowSet.Rows) == 0 {
+			r.trace.end(nil)
 			return false
 		}
 
 		r.offset = 0
-		r.rowSet = resp.Results
-		r.hasMore = *resp.HasMoreRows
+		r.rowSet = rowSet
 	}
 
 	row := r.rowSet.Rows[r.offset]
 	r.nextRow = make([]interface{}, len(r.Columns()))
 
 	if err := convertRow(row, r.nextRow); err != nil {
-		log.Printf("Error converting row: %v", err)
+		r.err = fmt.Errorf("Error converting row: %v", err)
+		r.trace.end(r.err)
 		return false
 	}
 	r.offset++
@@ -181,6 +272,131 @@ func (r *rowSet) Next() bool {
 	return true
 }
 
+// Returns the error that ended iteration with Next, if any, as
+// sql.Rows.Err does.
+func (r *rowSet) Err() error {
+	return r.err
+}
+
+// Returns the next batch of rows, from the prefetcher if Options enable
+// it, or nil if there are no more.
+func (r *rowSet) nextBatch() (*tcliservice.TRowSet, error) {
+	if r.options.PrefetchDepth <= 0 && r.batches == nil {
+		if !r.hasMore {
+			return nil, nil
+		}
+		return r.fetch()
+	}
+
+	r.startPrefetch(r.options.PrefetchDepth)
+
+	batch, ok := <-r.batches
+	if !ok {
+		return nil, nil
+	}
+
+	return batch.rows, batch.err
+}
+
+// Starts fetching batches in the background, up to depth batches ahead
+// of the one being consumed, unless that has already started.
+func (r *rowSet) startPrefetch(depth int) {
+	if r.batches == nil {
+		r.batches = make(chan fetchedBatch, depth)
+		r.stop = make(chan struct{})
+		go r.prefetch(r.batches, r.stop)
+	}
+}
+
+// Fetches the next batch of up to BatchSize rows from hive, recording
+// whether there are more to come.
+func (r *rowSet) fetch() (*tcliservice.TRowSet, error) {
+	fetchReq := tcliservice.NewTFetchResultsReq()
+	fetchReq.OperationHandle = *r.operation
+	fetchReq.Orientation = tcliservice.TFetchOrientation_FETCH_NEXT
+	fetchReq.MaxRows = r.batchSize()
+
+	span := r.trace.call("FetchResults")
+	resp, err := r.thrift.FetchResults(*fetchReq)
+	if err != nil {
+		err = fmt.Errorf("FetchResults failed: %v", err)
+	} else if !isSuccessStatus(resp.Status) {
+		err = fmt.Errorf("FetchResults failed: %s", resp.Status.String())
+	}
+	if err != nil {
+		span.End(err)
+		return nil, err
+	}
+
+	r.hasMore = resp.GetHasMoreRows()
+	if resp.Results == nil {
+		span.SetAttribute("hive.rows", 0)
+		span.End(nil)
+		return tcliservice.NewTRowSet(), nil
+	}
+	span.SetAttribute("hive.rows", len(resp.Results.Rows))
+	span.End(nil)
+
+	r.observeBatch(resp.Results)
+	return resp.Results, nil
+}
+
+// Stops any prefetching and releases the operation's resources on the
+// server. The RowSet can't be used afterwards.
+func (r *rowSet) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	if r.stop != nil {
+		close(r.stop)
+	}
+
+	err := r.closeOperation()
+	r.trace.end(nil)
+	return err
+}
+
+// What a cancelled query's span ends with.
+var errCancelled = errors.New("Query was cancelled")
+
+// Issue a request to cancel the operation. Safe to call while another
+// goroutine is in Wait, which then returns a CANCELED status.
+func (r *rowSet) Cancel() error {
+	req := tcliservice.NewTCancelOperationReq()
+	req.OperationHandle = *r.operation
+
+	resp, err := r.thrift.CancelOperation(*req)
+	if err != nil {
+		return fmt.Errorf("Error cancelling operation: %v", err)
+	}
+
+	if !isSuccessStatus(resp.Status) {
+		return fmt.Errorf("CancelOperation failed: %s", resp.Status.String())
+	}
+
+	r.trace.setAttribute("hive.operation_state", StateCanceled.String())
+	r.trace.end(errCancelled)
+	return nil
+}
+
+func (r *rowSet) closeOperation() error {
+	req := tcliservice.NewTCloseOperationReq()
+	req.OperationHandle = *r.operation
+
+	resp, err := r.thrift.CloseOperation(*req)
+	if err != nil {
+		return fmt.Errorf("Error closing operation: %+v, %v", resp, err)
+	}
+
+	if !isSuccessStatus(resp.Status) {
+		return fmt.Errorf("CloseOperation failed: %s", resp.Status.String())
+	}
+
+	return nil
+}
+
 // Scan the last row prepared via Next() into the destination(s) provided,
 // which must be pointers to value types, as in database.sql. Further,
 // only pointers of the following types are supported:
@@ -193,16 +409,29 @@ func (r *rowSet) Scan(dest ...interface{}) error {
 	// types where possible, as well as some common error checking,
 	// like passing nil. database/sql's method is very convenient,
 	// for example: http://golang.org/src/pkg/database/sql/convert.go, like 85
-	if r.nextRow == nil {
+	return scanRow(r.nextRow, dest)
+}
+
+func scanRow(row []interface{}, dest []interface{}) error {
+	if row == nil {
 		return errors.New("No row to scan! Did you call Next() first?")
 	}
 
-	if len(dest) != len(r.nextRow) {
-		return fmt.Errorf("Can't scan into %d arguments with input of length %d", len(dest), len(r.nextRow))
+	if len(dest) != len(row) {
+		return fmt.Errorf("Can't scan into %d arguments with input of length %d", len(dest), len(row))
 	}
 
-	for i, val := range r.nextRow {
+	for i, val := range row {
 		d := dest[i]
+		if p, ok := d.(*interface{}); ok {
+			*p = val
+			continue
+		}
+
+		if val == nil {
+			return fmt.Errorf("Can't scan NULL into %T; scan into *interface{} instead", d)
+		}
+
 		switch dt := d.(type) {
 		case *string:
 			switch st := val.(type) {
@@ -254,9 +483,12 @@ func (r *rowSet) Columns() []string {
 
 // Return a serialized representation of an identifier that can later
 // be used to reattach to a running operation. This identifier and
-// serialized representation should be considered opaque by users.
+// serialized representation should be considered opaque by users; it is
+// made of URL-safe characters, and ParseHandle describes it.
 func (r *rowSet) Handle() ([]byte, error) {
-	return serializeOp(r.operation)
+	info := r.source
+	info.Operation = r.operation
+	return info.encode()
 }
 
 func convertRow(row *tcliservice.TRow, dest []interface{}) error {
@@ -292,42 +524,24 @@ func convertColumn(col *tcliservice.TColumnValue) (interface{}, error) {
 	case col.DoubleVal.IsSetValue():
 		return col.DoubleVal.GetValue(), nil
 	default:
-		return nil, fmt.Errorf("Can't convert column value %v", col)
+		// No value set is how hive sends NULL.
+		return nil, nil
 	}
 }
 
 // Returns a string representation of operation status.
 func (s Status) String() string {
-	if s.state == nil {
-		return "unknown"
-	}
-	return s.state.String()
+	return s.State.String()
 }
 
 // Returns true if the job has completed or failed.
 func (s Status) IsComplete() bool {
-	if s.state == nil {
-		return false
-	}
-
-	switch *s.state {
-	case tcliservice.TOperationState_FINISHED_STATE,
-		tcliservice.TOperationState_CANCELED_STATE,
-		tcliservice.TOperationState_CLOSED_STATE,
-		tcliservice.TOperationState_ERROR_STATE:
-		return true
-	}
-
-	return false
+	return s.State.IsComplete()
 }
 
 // Returns true if the job compelted successfully.
 func (s Status) IsSuccess() bool {
-	if s.state == nil {
-		return false
-	}
-
-	return *s.state == tcliservice.TOperationState_FINISHED_STATE
+	return s.State == StateFinished
 }
 
 func deserializeOp(handle []byte) (*tcliservice.TOperationHandle, error) {
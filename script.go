@@ -0,0 +1,126 @@
+package hivething
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// Options for ExecScript.
+type ScriptOptions struct {
+	// Values substituted for ${hivevar:name} and ${name} references before
+	// each statement is sent. References to other variables are left for
+	// the server to substitute.
+	Vars map[string]string
+	// By default ExecScript stops at the first statement that fails. If
+	// true, it runs the remaining statements anyway.
+	ContinueOnError bool
+}
+
+// The outcome of one statement in a script. Rows may be read once
+// ExecScript returns, as with any other RowSet, and should be closed
+// afterwards. Rows is nil for statements that failed or have no result
+// columns, such as SET and DDL, whose operations ExecScript closes.
+type StatementResult struct {
+	Statement string
+	Rows      RowSet
+	Status    *Status
+	Error     error
+}
+
+// Execute a script of semicolon separated HiveQL statements, such as the
+// contents of a .hql file, one at a time on this connection's session,
+// waiting for each to complete before issuing the next. Returns a result
+// for every statement attempted, and the first statement error unless
+// opts.ContinueOnError is set.
+func (c *Connection) ExecScript(script io.Reader, opts ScriptOptions) ([]StatementResult, error) {
+	text, err := ioutil.ReadAll(script)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading script: %v", err)
+	}
+
+	var results []StatementResult
+	for i, statement := range splitStatements(string(text)) {
+		statement = substituteVars(statement, opts.Vars)
+		result := StatementResult{Statement: statement}
+
+		result.Rows, result.Error = c.Query(statement)
+		if result.Error == nil {
+			result.Status, result.Error = result.Rows.Wait()
+			if result.Error != nil || len(result.Rows.Columns()) == 0 {
+				result.Rows.Close()
+				result.Rows = nil
+			}
+		}
+
+		results = append(results, result)
+		if result.Error != nil && !opts.ContinueOnError {
+			return results, fmt.Errorf("Error in statement %d: %v", i+1, result.Error)
+		}
+	}
+
+	return results, nil
+}
+
+// Splits a script on semicolons that aren't inside string literals,
+// quoted identifiers or comments. -- comments are dropped, as the hive
+// CLI does, but /* */ comments are kept since they may carry hints.
+// Statements that are empty once trimmed are skipped. Text after the last
+// semicolon is returned as rest, trimmed, so an interactive shell can tell
+// whether the last statement is complete.
+func SplitScript(script string) (statements []string, rest string) {
+	var current bytes.Buffer
+
+	for i := 0; i < len(script); {
+		if j := skipNonCode(script, i); j > i {
+			if script[i] == '-' {
+				current.WriteByte('\n')
+			} else {
+				current.WriteString(script[i:j])
+			}
+			i = j
+			continue
+		}
+
+		if script[i] == ';' {
+			if statement := strings.TrimSpace(current.String()); statement != "" {
+				statements = append(statements, statement)
+			}
+			current.Reset()
+		} else {
+			current.WriteByte(script[i])
+		}
+		i++
+	}
+
+	return statements, strings.TrimSpace(current.String())
+}
+
+// Splits a script into statements, including any after the last semicolon.
+func splitStatements(script string) []string {
+	statements, rest := SplitScript(script)
+	if rest != "" {
+		statements = append(statements, rest)
+	}
+
+	return statements
+}
+
+var varPattern = regexp.MustCompile(`\$\{(hivevar:)?([^:}]+)\}`)
+
+func substituteVars(statement string, vars map[string]string) string {
+	if len(vars) == 0 {
+		return statement
+	}
+
+	return varPattern.ReplaceAllStringFunc(statement, func(ref string) string {
+		name := varPattern.FindStringSubmatch(ref)[2]
+		if val, ok := vars[name]; ok {
+			return val
+		}
+		return ref
+	})
+}
@@ -0,0 +1,60 @@
+package hivething
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- load the day's events
+SET hive.exec.dynamic.partition=true;
+SET hivevar:day=2014-06-01;
+
+INSERT OVERWRITE TABLE events PARTITION (day)
+SELECT /*+ MAPJOIN(k) */ e.*, 'a;b' AS ` + "`x;y`" + ` -- trailing; comment
+FROM raw e JOIN kinds k ON e.kind = k.id;
+/* done; */ SELECT "it\"s; fine";;
+`
+
+	expected := []string{
+		"SET hive.exec.dynamic.partition=true",
+		"SET hivevar:day=2014-06-01",
+		"INSERT OVERWRITE TABLE events PARTITION (day)\nSELECT /*+ MAPJOIN(k) */ e.*, 'a;b' AS `x;y` \nFROM raw e JOIN kinds k ON e.kind = k.id",
+		`/* done; */ SELECT "it\"s; fine"`,
+	}
+
+	if statements := splitStatements(script); !reflect.DeepEqual(statements, expected) {
+		t.Errorf("Expected statements %q but got %q", expected, statements)
+	}
+}
+
+func TestSplitScriptRest(t *testing.T) {
+	tests := []struct {
+		script     string
+		statements []string
+		rest       string
+	}{
+		{"select 1;\nselect 2", []string{"select 1"}, "select 2"},
+		{"select 1; -- done\n", []string{"select 1"}, ""},
+		{"select 'a;\n", nil, "select 'a;"},
+		{"select 1 /* ; */\n", nil, "select 1 /* ; */"},
+	}
+
+	for _, test := range tests {
+		statements, rest := SplitScript(test.script)
+		if !reflect.DeepEqual(statements, test.statements) || rest != test.rest {
+			t.Errorf("Expected %q to split into %q and rest %q but got %q and %q", test.script, test.statements, test.rest, statements, rest)
+		}
+	}
+}
+
+func TestSubstituteVars(t *testing.T) {
+	vars := map[string]string{"day": "2014-06-01", "tbl": "events"}
+
+	statement := substituteVars("SELECT * FROM ${tbl} WHERE day = '${hivevar:day}' AND x = '${hiveconf:day}' AND y = '${other}'", vars)
+	expected := "SELECT * FROM events WHERE day = '2014-06-01' AND x = '${hiveconf:day}' AND y = '${other}'"
+
+	if statement != expected {
+		t.Errorf("Expected %q but got %q", expected, statement)
+	}
+}
@@ -0,0 +1,123 @@
+package hivething
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/derekgr/hivething/TCLIService"
+)
+
+// The state of an operation, as hiveserver2 reports it.
+type OperationState int
+
+const (
+	// The zero value, for a state not yet known or one hivething doesn't
+	// recognize.
+	StateUnknown OperationState = iota
+	StateInitialized
+	StatePending
+	StateRunning
+	StateFinished
+	StateCanceled
+	StateClosed
+	StateError
+)
+
+var operationStates = map[tcliservice.TOperationState]OperationState{
+	tcliservice.TOperationState_INITIALIZED_STATE: StateInitialized,
+	tcliservice.TOperationState_PENDING_STATE:     StatePending,
+	tcliservice.TOperationState_RUNNING_STATE:     StateRunning,
+	tcliservice.TOperationState_FINISHED_STATE:    StateFinished,
+	tcliservice.TOperationState_CANCELED_STATE:    StateCanceled,
+	tcliservice.TOperationState_CLOSED_STATE:      StateClosed,
+	tcliservice.TOperationState_ERROR_STATE:       StateError,
+}
+
+func newOperationState(state *tcliservice.TOperationState) OperationState {
+	if state == nil {
+		return StateUnknown
+	}
+	return operationStates[*state]
+}
+
+// Returns the state's name as hive gives it, such as "RUNNING_STATE".
+func (s OperationState) String() string {
+	switch s {
+	case StateInitialized:
+		return "INITIALIZED_STATE"
+	case StatePending:
+		return "PENDING_STATE"
+	case StateRunning:
+		return "RUNNING_STATE"
+	case StateFinished:
+		return "FINISHED_STATE"
+	case StateCanceled:
+		return "CANCELED_STATE"
+	case StateClosed:
+		return "CLOSED_STATE"
+	case StateError:
+		return "ERROR_STATE"
+	}
+	return "UNKNOWN_STATE"
+}
+
+// Returns true if an operation in this state has finished, failed, or
+// been cancelled or closed.
+func (s OperationState) IsComplete() bool {
+	switch s {
+	case StateFinished, StateCanceled, StateClosed, StateError:
+		return true
+	}
+	return false
+}
+
+// An error reported by hiveserver2: why an operation failed, or why a
+// call was refused.
+type ServerError struct {
+	// The call that was refused, such as "OpenSession", or "" for an
+	// operation that failed.
+	Call string
+	// The status of the refused call, or ERROR_STATUS for an operation
+	// that failed.
+	StatusCode tcliservice.TStatusCode
+	// The ISO/IEC SQLSTATE, such as "42000" for a syntax error, if given.
+	SQLState string
+	// Hive's error code, such as 10001 for a missing table, if given.
+	Code    int32
+	Message string
+}
+
+// Formats the error as beeline does, after the refused call if any.
+func (e *ServerError) Error() string {
+	msg := fmt.Sprintf("%s (state=%s,code=%d)", e.Message, e.SQLState, e.Code)
+	if e.Call != "" {
+		code := strings.TrimPrefix(e.StatusCode.String(), "TStatusCode_")
+		return fmt.Sprintf("%s failed with %s: %s", e.Call, code, msg)
+	}
+	return msg
+}
+
+func newServerError(call string, status tcliservice.TStatus) *ServerError {
+	return &ServerError{
+		Call:       call,
+		StatusCode: status.StatusCode,
+		SQLState:   status.GetSqlState(),
+		Code:       status.GetErrorCode(),
+		Message:    status.GetErrorMessage(),
+	}
+}
+
+// Returns the error an operation failed with, from a status response, or
+// nil if the server didn't say, as older servers never do.
+func operationError(resp tcliservice.TGetOperationStatusResp) error {
+	if !resp.IsSetErrorMessage() && !resp.IsSetSqlState() && !resp.IsSetErrorCode() {
+		return nil
+	}
+
+	return &ServerError{
+		StatusCode: tcliservice.TStatusCode_ERROR_STATUS,
+		SQLState:   resp.GetSqlState(),
+		Code:       resp.GetErrorCode(),
+		Message:    resp.GetErrorMessage(),
+	}
+}
@@ -0,0 +1,143 @@
+package hivething
+
+import (
+	"context"
+
+	"github.com/derekgr/hivething/TCLIService"
+)
+
+// A row delivered by Stream, holding the values a RowSet would Scan.
+type Row []interface{}
+
+// Scan the row into the destination(s) provided, as RowSet.Scan does.
+func (r Row) Scan(dest ...interface{}) error {
+	return scanRow(r, dest)
+}
+
+type fetchedBatch struct {
+	rows *tcliservice.TRowSet
+	err  error
+}
+
+// Stream the operation's rows over a channel, buffering up to bufferSize
+// of them, after waiting for the operation to succeed if necessary. A
+// background goroutine fetches the next batch from hive while the
+// current one is being consumed. Both channels are closed when the rows
+// run out, an error occurs, or ctx is done; at most one error is sent.
+// The RowSet is closed afterwards, so it can't be used again, and
+// shouldn't be used at all while streaming.
+func (r *rowSet) Stream(ctx context.Context, bufferSize int) (<-chan Row, <-chan error) {
+	rows := make(chan Row, bufferSize)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(rows)
+		defer r.Close()
+
+		if err := r.waitForSuccess(); err != nil {
+			errs <- err
+			return
+		}
+
+		emit := func(batch []*tcliservice.TRow) error {
+			for _, row := range batch {
+				vals := make(Row, len(r.columns))
+				if err := convertRow(row, vals); err != nil {
+					return err
+				}
+
+				select {
+				case rows <- vals:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return nil
+		}
+
+		// Rows left over from earlier calls to Next.
+		if r.rowSet != nil && r.offset < len(r.rowSet.Rows) {
+			if err := emit(r.rowSet.Rows[r.offset:]); err != nil {
+				errs <- err
+				return
+			}
+		}
+
+		depth := r.options.PrefetchDepth
+		if depth < 1 {
+			depth = 1
+		}
+		r.startPrefetch(depth)
+
+		for {
+			var batch fetchedBatch
+			select {
+			case b, ok := <-r.batches:
+				if !ok {
+					return
+				}
+				batch = b
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+
+			if batch.err == nil {
+				batch.err = emit(batch.rows.Rows)
+			}
+
+			if batch.err != nil {
+				errs <- batch.err
+				return
+			}
+		}
+	}()
+
+	return rows, errs
+}
+
+// Fetches batches until there are no more rows, an error occurs, or stop
+// is closed, then closes batches.
+func (r *rowSet) prefetch(batches chan<- fetchedBatch, stop <-chan struct{}) {
+	defer close(batches)
+
+	for r.hasMore {
+		rows, err := r.fetch()
+
+		select {
+		case batches <- fetchedBatch{rows, err}:
+		case <-stop:
+			return
+		}
+
+		if err != nil || len(rows.Rows) == 0 {
+			return
+		}
+	}
+}
+
+func (r *staticRowSet) Stream(ctx context.Context, bufferSize int) (<-chan Row, <-chan error) {
+	rows := make(chan Row, bufferSize)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(rows)
+
+		for r.Next() {
+			select {
+			case rows <- Row(r.rows[r.offset-1]):
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return rows, errs
+}
+
+func (r *staticRowSet) Close() error {
+	return nil
+}
@@ -0,0 +1,115 @@
+package hivething
+
+import (
+	"sync"
+
+	"github.com/derekgr/hivething/TCLIService"
+)
+
+// A thrift client is a single request/response stream, so calls issued from
+// more than one goroutine (e.g. a Submit callback polling while the caller
+// issues another query) must take turns.
+type syncClient struct {
+	mu     sync.Mutex
+	client tcliservice.TCLIService
+}
+
+func newSyncClient(client tcliservice.TCLIService) *syncClient {
+	return &syncClient{client: client}
+}
+
+func (s *syncClient) OpenSession(req tcliservice.TOpenSessionReq) (tcliservice.TOpenSessionResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client.OpenSession(req)
+}
+
+func (s *syncClient) CloseSession(req tcliservice.TCloseSessionReq) (tcliservice.TCloseSessionResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client.CloseSession(req)
+}
+
+func (s *syncClient) GetInfo(req tcliservice.TGetInfoReq) (tcliservice.TGetInfoResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client.GetInfo(req)
+}
+
+func (s *syncClient) ExecuteStatement(req tcliservice.TExecuteStatementReq) (tcliservice.TExecuteStatementResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client.ExecuteStatement(req)
+}
+
+func (s *syncClient) GetTypeInfo(req tcliservice.TGetTypeInfoReq) (tcliservice.TGetTypeInfoResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client.GetTypeInfo(req)
+}
+
+func (s *syncClient) GetCatalogs(req tcliservice.TGetCatalogsReq) (tcliservice.TGetCatalogsResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client.GetCatalogs(req)
+}
+
+func (s *syncClient) GetSchemas(req tcliservice.TGetSchemasReq) (tcliservice.TGetSchemasResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client.GetSchemas(req)
+}
+
+func (s *syncClient) GetTables(req tcliservice.TGetTablesReq) (tcliservice.TGetTablesResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client.GetTables(req)
+}
+
+func (s *syncClient) GetTableTypes(req tcliservice.TGetTableTypesReq) (tcliservice.TGetTableTypesResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client.GetTableTypes(req)
+}
+
+func (s *syncClient) GetColumns(req tcliservice.TGetColumnsReq) (tcliservice.TGetColumnsResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client.GetColumns(req)
+}
+
+func (s *syncClient) GetFunctions(req tcliservice.TGetFunctionsReq) (tcliservice.TGetFunctionsResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client.GetFunctions(req)
+}
+
+func (s *syncClient) GetOperationStatus(req tcliservice.TGetOperationStatusReq) (tcliservice.TGetOperationStatusResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client.GetOperationStatus(req)
+}
+
+func (s *syncClient) CancelOperation(req tcliservice.TCancelOperationReq) (tcliservice.TCancelOperationResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client.CancelOperation(req)
+}
+
+func (s *syncClient) CloseOperation(req tcliservice.TCloseOperationReq) (tcliservice.TCloseOperationResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client.CloseOperation(req)
+}
+
+func (s *syncClient) GetResultSetMetadata(req tcliservice.TGetResultSetMetadataReq) (tcliservice.TGetResultSetMetadataResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client.GetResultSetMetadata(req)
+}
+
+func (s *syncClient) FetchResults(req tcliservice.TFetchResultsReq) (tcliservice.TFetchResultsResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client.FetchResults(req)
+}
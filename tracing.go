@@ -0,0 +1,92 @@
+package hivething
+
+import (
+	"context"
+	"sync"
+	"unicode/utf8"
+)
+
+// The most bytes of a query's text recorded in its span.
+const maxTracedStatementBytes = 1024
+
+// Starts spans tracing queries; see the oteltracing package for
+// OpenTelemetry. A query issued with QueryContext gets a span that is a
+// child of any in the context, and ends when the RowSet is closed,
+// cancelled, or runs out of rows, or the query fails. The calls made to
+// run it and fetch its results get spans that are children of the query's.
+type Tracer interface {
+	// Starts a span named name, such as "hivething.FetchResults", as a child
+	// of any span in ctx. Returns the span, and a context carrying it.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// A span started by a Tracer.
+type Span interface {
+	// Records an attribute of the span's operation. Values are strings,
+	// ints or int64s.
+	SetAttribute(key string, value interface{})
+	// Ends the span, recording that its operation failed if err isn't nil.
+	End(err error)
+}
+
+// Used when a query isn't traced.
+type noSpan struct{}
+
+func (noSpan) SetAttribute(key string, value interface{}) {}
+func (noSpan) End(err error)                              {}
+
+// The trace of a query: its span, the context carrying it, and whether
+// it has ended.
+type queryTrace struct {
+	ctx    context.Context
+	tracer Tracer
+	span   Span
+	once   sync.Once
+}
+
+// Starts tracing a query, recording its text as given, before arguments
+// are bound, so that their values aren't recorded, and truncated. Returns
+// nil if options has no Tracer.
+func startQueryTrace(ctx context.Context, options Options, query string) *queryTrace {
+	if options.Tracer == nil {
+		return nil
+	}
+
+	if len(query) > maxTracedStatementBytes {
+		cut := maxTracedStatementBytes
+		for cut > 0 && !utf8.RuneStart(query[cut]) {
+			cut--
+		}
+		query = query[:cut] + "..."
+	}
+
+	ctx, span := options.Tracer.Start(ctx, "hivething.Query")
+	span.SetAttribute("db.system", "hive")
+	span.SetAttribute("db.statement", query)
+
+	return &queryTrace{ctx: ctx, tracer: options.Tracer, span: span}
+}
+
+// Starts a span for a call made for the query, named after its method.
+func (t *queryTrace) call(method string) Span {
+	if t == nil {
+		return noSpan{}
+	}
+
+	_, span := t.tracer.Start(t.ctx, "hivething."+method)
+	return span
+}
+
+// Records an attribute of the query.
+func (t *queryTrace) setAttribute(key string, value interface{}) {
+	if t != nil {
+		t.span.SetAttribute(key, value)
+	}
+}
+
+// Ends the query's span, unless it has ended already.
+func (t *queryTrace) end(err error) {
+	if t != nil {
+		t.once.Do(func() { t.span.End(err) })
+	}
+}